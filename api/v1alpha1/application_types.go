@@ -17,7 +17,12 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type ApplicationDomain struct {
@@ -25,6 +30,152 @@ type ApplicationDomain struct {
 	TLSCertSecretName string `json:"tlsCertSecretName,omitempty"`
 }
 
+// RevisionRetention limits how many ApplicationRevisions are kept. The current
+// revision, the one before it and pinned revisions are always kept.
+type RevisionRetention struct {
+	// Count is the number of most recent revisions to keep, 0 keeps all of them
+	Count int `json:"count,omitempty"`
+	// MaxAge prunes revisions older than this, e.g. 720h, unset keeps all of them
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// DeploymentStrategyType is how a new revision replaces the running one
+// +kubebuilder:validation:Enum=rolling;bluegreen;canary
+type DeploymentStrategyType string
+
+const (
+	// RollingStrategy updates the application's Deployment in place
+	RollingStrategy DeploymentStrategyType = "rolling"
+	// BlueGreenStrategy gives every revision its own Deployment and switches
+	// the Service over once the new one is fully ready
+	BlueGreenStrategy DeploymentStrategyType = "bluegreen"
+	// CanaryStrategy runs a new revision next to the current one and moves
+	// traffic over to it in steps, aborting when it goes bad
+	CanaryStrategy DeploymentStrategyType = "canary"
+)
+
+type BlueGreenOptions struct {
+	// KeepPreviousFor is how long the Deployment traffic was switched away from
+	// is kept running so switching back is instant, defaults to 30m
+	KeepPreviousFor *metav1.Duration `json:"keepPreviousFor,omitempty"`
+}
+
+type CanaryStep struct {
+	// Weight is the percentage of traffic the canary gets during the step
+	Weight int `json:"weight"`
+	// Duration is how long the step lasts once the canary is ready
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// Pause holds the canary at this step until it's resumed with
+	// feisty releases:resume
+	Pause bool `json:"pause,omitempty"`
+}
+
+// CanaryTrafficRouting is how traffic is split between the canary and stable pods
+// +kubebuilder:validation:Enum=replicas;nginx
+type CanaryTrafficRouting string
+
+const (
+	// CanaryReplicaRouting splits traffic by the ratio of canary to stable
+	// replicas, the stable pods keep at least one replica until the last step
+	// so the split is coarse for applications with few replicas
+	CanaryReplicaRouting CanaryTrafficRouting = "replicas"
+	// CanaryNginxRouting splits the ingress traffic with the canary annotations
+	// of the NGINX ingress controller, it needs routing to be enabled
+	CanaryNginxRouting CanaryTrafficRouting = "nginx"
+)
+
+type CanaryOptions struct {
+	// Steps the canary goes through, it gets all the traffic after the last one
+	Steps []CanaryStep `json:"steps,omitempty"`
+	// TrafficRouting defaults to replicas
+	TrafficRouting CanaryTrafficRouting `json:"trafficRouting,omitempty"`
+	// MaxRestarts is how many times the containers of the canary may restart in
+	// total before it's aborted, defaults to 2
+	MaxRestarts *int `json:"maxRestarts,omitempty"`
+}
+
+// ExtraContainer is a container running next to the application's, e.g. a
+// database proxy or a log shipper, or an init container running before it
+type ExtraContainer struct {
+	// Name must differ from the application's name, which names its container
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name    string          `json:"name"`
+	Image   string          `json:"image"`
+	Command []string        `json:"command,omitempty"`
+	Args    []string        `json:"args,omitempty"`
+	Env     []corev1.EnvVar `json:"env,omitempty"`
+	// Ports the container listens on, they aren't exposed by the Service
+	Ports []corev1.ContainerPort `json:"ports,omitempty"`
+	// InheritConfig gives the container the application's config as
+	// environment variables
+	InheritConfig bool `json:"inheritConfig,omitempty"`
+	// VolumeMounts mount the application's volumes, by name
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+// PersistentVolumeOptions describe the PersistentVolumeClaim the controller
+// creates for a volume, named <application>-<volume>
+type PersistentVolumeOptions struct {
+	Size resource.Quantity `json:"size"`
+	// StorageClassName defaults to the cluster's default storage class
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// AccessMode defaults to ReadWriteOnce, which can only be attached to one
+	// node at a time so it's limited to a single replica, whose pod is
+	// replaced rather than rolled
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadOnlyMany;ReadWriteMany
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+	// Keep leaves the PersistentVolumeClaim and its data behind when the
+	// application is deleted or stops declaring the volume
+	Keep bool `json:"keep,omitempty"`
+}
+
+// EmptyDirOptions describe scratch storage that lives as long as the pod
+type EmptyDirOptions struct {
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+	// Medium is empty for the node's disk, Memory for a tmpfs
+	Medium corev1.StorageMedium `json:"medium,omitempty"`
+}
+
+// ApplicationVolume is mounted in the application's container, only one of
+// Persistent, EmptyDir, ConfigMap and Secret may be set
+type ApplicationVolume struct {
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// MountPath is where the volume is mounted in the application's container,
+	// a volume without one is only mounted by sidecars
+	MountPath  string                   `json:"mountPath,omitempty"`
+	ReadOnly   bool                     `json:"readOnly,omitempty"`
+	Persistent *PersistentVolumeOptions `json:"persistent,omitempty"`
+	EmptyDir   *EmptyDirOptions         `json:"emptyDir,omitempty"`
+	// ConfigMap is the name of a ConfigMap whose keys are mounted as files
+	ConfigMap string `json:"configMap,omitempty"`
+	// Secret is the name of a Secret whose keys are mounted as files
+	Secret string `json:"secret,omitempty"`
+}
+
+// TopologySpread spreads the application's pods evenly across the groups of
+// nodes sharing a label value, e.g. the zones with topology.kubernetes.io/zone
+type TopologySpread struct {
+	TopologyKey string `json:"topologyKey"`
+	// MaxSkew is the largest allowed difference in pods between two groups,
+	// defaults to 1
+	MaxSkew int32 `json:"maxSkew,omitempty"`
+	// WhenUnsatisfiable defaults to DoNotSchedule
+	// +kubebuilder:validation:Enum=DoNotSchedule;ScheduleAnyway
+	WhenUnsatisfiable corev1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+}
+
+// Scheduling decides which nodes the application's pods run on. The
+// scheduling annotation of the namespace holds defaults in the same format.
+type Scheduling struct {
+	NodeSelector   map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations    []corev1.Toleration `json:"tolerations,omitempty"`
+	Affinity       *corev1.Affinity    `json:"affinity,omitempty"`
+	TopologySpread []TopologySpread    `json:"topologySpread,omitempty"`
+	// Spread prefers running the pods on different nodes
+	Spread *bool `json:"spread,omitempty"`
+}
+
 // ApplicationSpec defines the desired state of Application
 type ApplicationSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
@@ -35,15 +186,201 @@ type ApplicationSpec struct {
 	Port           int                 `json:"port,omitempty"`
 	RestartTime    string              `json:"restartTime,omitempty"`
 	AppConfigRef   string              `json:"appConfigRef,omitempty"`
+	// RevisionRetention overrides the operator wide revision retention
+	RevisionRetention *RevisionRetention `json:"revisionRetention,omitempty"`
+	// AutoRollback restores the last succeeded revision when a rollout fails
+	AutoRollback bool `json:"autoRollback,omitempty"`
+	// Strategy is how new revisions are rolled out, defaults to rolling
+	Strategy  DeploymentStrategyType `json:"strategy,omitempty"`
+	BlueGreen *BlueGreenOptions      `json:"blueGreen,omitempty"`
+	Canary    *CanaryOptions         `json:"canary,omitempty"`
+	// MaxSurge is how many pods, or what percentage of replicas, may be created
+	// above the replica count during a rollout
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// MaxUnavailable is how many pods, or what percentage of replicas, may be
+	// unavailable during a rollout
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// MinReadySeconds is how long a new pod must be ready before it counts as
+	// available
+	MinReadySeconds int `json:"minReadySeconds,omitempty"`
+	// ProgressDeadlineSeconds is how long a rollout may go without progress
+	// before it's failed, defaults to 600
+	ProgressDeadlineSeconds *int `json:"progressDeadlineSeconds,omitempty"`
+	// Maintenance sends the application's ingress traffic to a maintenance page
+	// answering 503, the pods keep running
+	Maintenance bool `json:"maintenance,omitempty"`
+	// MaintenancePage is the HTML of the maintenance page, a generic page is
+	// used when it's empty
+	MaintenancePage string `json:"maintenancePage,omitempty"`
+	// StoppedFormation holds the replicas of each process type the application
+	// ran before it was stopped, keyed by process type, it's nil while the
+	// application runs
+	StoppedFormation map[string]int `json:"stoppedFormation,omitempty"`
+	// SleepAfter scales the application to zero once it got no requests for
+	// that long, the next request wakes it back up. It needs the wake-up proxy.
+	SleepAfter *metav1.Duration `json:"sleepAfter,omitempty"`
+	// Sidecars run in every pod next to the application's container
+	Sidecars []ExtraContainer `json:"sidecars,omitempty"`
+	// InitContainers run in order before the containers of every pod start
+	InitContainers []ExtraContainer `json:"initContainers,omitempty"`
+	// Volumes are mounted in the application's container and can be mounted
+	// by its sidecars and init containers
+	Volumes []ApplicationVolume `json:"volumes,omitempty"`
+	// Scheduling is merged over the defaults of the namespace
+	Scheduling *Scheduling `json:"scheduling,omitempty"`
+}
+
+type ApplicationConditionType string
+
+const (
+	// ApplicationRevisionRecorded is false when the current state of the
+	// application couldn't be saved as a revision
+	ApplicationRevisionRecorded ApplicationConditionType = "RevisionRecorded"
+	// ApplicationMaintenance is true while the application is in maintenance
+	ApplicationMaintenance ApplicationConditionType = "Maintenance"
+	// ApplicationStopped is true while the application runs no pods, either
+	// because it was scaled to zero or it has no image yet
+	ApplicationStopped ApplicationConditionType = "Stopped"
+	// ApplicationContainersValid is false when the sidecars or init containers
+	// can't be added to the pods, nothing is rolled out until they're fixed
+	ApplicationContainersValid ApplicationConditionType = "ContainersValid"
+	// ApplicationVolumesReady is false when a volume is invalid or its
+	// PersistentVolumeClaim couldn't be made, nothing is rolled out until then
+	ApplicationVolumesReady ApplicationConditionType = "VolumesReady"
+	// ApplicationRevisionsPruned is false when the revisions past the
+	// retention policy couldn't be pruned
+	ApplicationRevisionsPruned ApplicationConditionType = "RevisionsPruned"
+)
+
+// StrategyOverriddenReason is the reason of the VolumesReady condition when a
+// volume only one node can attach makes the application roll out with the
+// rolling strategy rather than the one it asked for
+const StrategyOverriddenReason = "StrategyOverridden"
+
+type ApplicationCondition struct {
+	Type               ApplicationConditionType `json:"type"`
+	Status             corev1.ConditionStatus   `json:"status"`
+	Reason             string                   `json:"reason,omitempty"`
+	Message            string                   `json:"message,omitempty"`
+	LastTransitionTime metav1.Time              `json:"lastTransitionTime,omitempty"`
+}
+
+// BlueGreenStatus tracks the Deployments of the bluegreen strategy
+type BlueGreenStatus struct {
+	// ActiveDeployment is the Deployment the Service sends traffic to
+	ActiveDeployment string `json:"activeDeployment,omitempty"`
+	// PreviewDeployment is the Deployment of a newer revision that isn't fully
+	// ready yet
+	PreviewDeployment string `json:"previewDeployment,omitempty"`
+	// PreviousDeployment is the Deployment traffic was last switched away from,
+	// it's kept until ScaleDownTime
+	PreviousDeployment string       `json:"previousDeployment,omitempty"`
+	ScaleDownTime      *metav1.Time `json:"scaleDownTime,omitempty"`
+	// SwitchTime is when traffic was last switched
+	SwitchTime *metav1.Time `json:"switchTime,omitempty"`
+}
+
+type CanaryPhase string
+
+const (
+	CanaryProgressing CanaryPhase = "Progressing"
+	CanaryPaused      CanaryPhase = "Paused"
+	CanaryAborted     CanaryPhase = "Aborted"
+	CanaryPromoted    CanaryPhase = "Promoted"
+)
+
+// CanaryStatus tracks the Deployments and progress of the canary strategy
+type CanaryStatus struct {
+	// StableDeployment is the Deployment of the last promoted revision
+	StableDeployment string `json:"stableDeployment,omitempty"`
+	// CanaryDeployment is the Deployment of the revision being rolled out
+	CanaryDeployment string `json:"canaryDeployment,omitempty"`
+	// AbortedDeployment is the Deployment of the canary that was aborted, it's
+	// kept scaled down and isn't retried until the application changes
+	AbortedDeployment string `json:"abortedDeployment,omitempty"`
+	// Step is the index of the step the canary is at, it's the number of steps
+	// once the canary gets all the traffic
+	Step int `json:"step,omitempty"`
+	// Steps is the number of steps the canary goes through
+	Steps int `json:"steps,omitempty"`
+	// Weight is the percentage of traffic the canary gets
+	Weight int `json:"weight,omitempty"`
+	// StepStartTime is when the canary became ready at the current step
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+	Phase         CanaryPhase  `json:"phase,omitempty"`
+	Message       string       `json:"message,omitempty"`
+}
+
+// ResumeKey identifies the step the canary is at, setting the canary resume
+// annotation to it resumes the canary when it's paused there
+func (in CanaryStatus) ResumeKey() string {
+	return in.CanaryDeployment + "/" + strconv.Itoa(in.Step)
+}
+
+// SleepStatus tracks the requests of an application that sleeps when idle
+type SleepStatus struct {
+	// Asleep is true while the application is scaled to zero for being idle
+	Asleep bool `json:"asleep,omitempty"`
+	// LastActivityTime is when the wake-up proxy last reported a request, or
+	// when sleeping was enabled
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
+	// SleepTime is when the application last went to sleep
+	SleepTime *metav1.Time `json:"sleepTime,omitempty"`
+	// WakeTime is when a request last woke the application up
+	WakeTime *metav1.Time `json:"wakeTime,omitempty"`
 }
 
 // ApplicationStatus defines the observed state of Application
 type ApplicationStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the generation of the spec the controller last reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// PrunedRevisions are the revision numbers deleted by the last prune
+	PrunedRevisions []int `json:"prunedRevisions,omitempty"`
+	// LastPruneTime is when revisions were last pruned
+	LastPruneTime *metav1.Time `json:"lastPruneTime,omitempty"`
+	// Revision is the number of the application's current revision, it's only
+	// ever increased and the controller relies on the optimistic concurrency
+	// of status updates to never hand out the same number twice
+	Revision int `json:"revision,omitempty"`
+	// Conditions describe problems the controller ran into
+	Conditions []ApplicationCondition `json:"conditions,omitempty"`
+	// BlueGreen is only set while the application has bluegreen Deployments
+	BlueGreen *BlueGreenStatus `json:"blueGreen,omitempty"`
+	// Canary is only set while the application has canary Deployments
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// Sleep is only set while the application sleeps when idle
+	Sleep *SleepStatus `json:"sleep,omitempty"`
+}
+
+// DeploymentName returns the name of the Deployment rolling out the
+// application's current revision
+func (in ApplicationStatus) DeploymentName(appName string) string {
+	if in.BlueGreen != nil {
+		if in.BlueGreen.PreviewDeployment != "" {
+			return in.BlueGreen.PreviewDeployment
+		}
+
+		if in.BlueGreen.ActiveDeployment != "" {
+			return in.BlueGreen.ActiveDeployment
+		}
+	}
+
+	if in.Canary != nil {
+		for _, name := range []string{in.Canary.CanaryDeployment, in.Canary.AbortedDeployment, in.Canary.StableDeployment} {
+			if name != "" {
+				return name
+			}
+		}
+	}
+
+	return appName
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 // Application is the Schema for the applications API
 type Application struct {
@@ -32,9 +32,21 @@ type ApplicationConfigSpec struct {
 type ApplicationConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// SecretName is the Secret holding the current config
+	SecretName string `json:"secretName,omitempty"`
+	// RetainedSecrets are older config Secrets kept because a retained revision references them
+	RetainedSecrets []string `json:"retainedSecrets,omitempty"`
+	// InUseSecrets are unreferenced config Secrets that will be deleted once no pod uses them
+	InUseSecrets []string `json:"inUseSecrets,omitempty"`
+	// DeletedSecrets are the config Secrets deleted by the last garbage collection
+	DeletedSecrets []string `json:"deletedSecrets,omitempty"`
+	// LastGCTime is when config Secrets were last deleted
+	LastGCTime *metav1.Time `json:"lastGCTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 // ApplicationConfig is the Schema for the applicationconfigs API
 type ApplicationConfig struct {
@@ -23,19 +23,60 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// RevisionChange describes who made the change that produced a revision and why
+type RevisionChange struct {
+	// Author is the user that made the change
+	Author string `json:"author,omitempty"`
+	// Cause is the kind of change: deploy, config, scale, rollback, restart, update or promote
+	Cause string `json:"cause,omitempty"`
+	// Summary is a human readable description of the change, e.g. "Set DATABASE_URL, removed FOO"
+	Summary string `json:"summary,omitempty"`
+	// Message is an optional message given with the change
+	Message string `json:"message,omitempty"`
+	// PromotedFrom is the release the image was promoted from, as
+	// namespace/revision, when the change was a pipeline promotion
+	PromotedFrom string `json:"promotedFrom,omitempty"`
+}
+
 // ApplicationRevisionSpec defines the desired state of ApplicationRevision
 type ApplicationRevisionSpec struct {
-	App     ApplicationSpec       `json:"app,omitempty"`
-	Cfg     ApplicationConfigSpec `json:"cfg,omitempty"`
-	AppHash string                `json:"appHash,omitempty"`
-	CfgHash string                `json:"cfgHash,omitempty"`
+	App ApplicationSpec       `json:"app,omitempty"`
+	Cfg ApplicationConfigSpec `json:"cfg,omitempty"`
+	// AppHash and CfgHash are SHA-256 hashes of the canonical App and Cfg,
+	// revisions created by older versions hold MD5 hashes instead
+	AppHash string `json:"appHash,omitempty"`
+	CfgHash string `json:"cfgHash,omitempty"`
+	// ImageDigest is the digest (sha256:...) App.Image resolved to once the
+	// revision's pods were running, it's empty until then
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Change records who changed what and why
+	Change RevisionChange `json:"change,omitempty"`
 }
 
+// RevisionPhase is where a revision is in its rollout
+type RevisionPhase string
+
+const (
+	RevisionPending    RevisionPhase = "Pending"
+	RevisionRollingOut RevisionPhase = "RollingOut"
+	RevisionSucceeded  RevisionPhase = "Succeeded"
+	RevisionFailed     RevisionPhase = "Failed"
+	RevisionSuperseded RevisionPhase = "Superseded"
+)
+
 // ApplicationRevisionStatus defines the observed state of ApplicationRevision
 type ApplicationRevisionStatus struct {
+	// Phase is where the revision is in its rollout, revisions replaced before
+	// finishing their rollout are Superseded
+	Phase RevisionPhase `json:"phase,omitempty"`
+	// Message explains the phase, e.g. why the rollout failed
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when the phase last changed
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 // ApplicationRevision is the Schema for the applicationrevisions API
 type ApplicationRevision struct {
@@ -0,0 +1,76 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PipelineStage is a step of a pipeline, run by a single Application
+type PipelineStage struct {
+	// Name of the stage, e.g. review, staging or production
+	Name string `json:"name"`
+	// App is the name of the stage's Application
+	App string `json:"app"`
+	// Namespace of the Application, defaults to the app name like the CLI does
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// StageNamespace returns the namespace of a stage's Application
+func (s PipelineStage) StageNamespace() string {
+	if s.Namespace != "" {
+		return s.Namespace
+	}
+
+	return s.App
+}
+
+// PipelineSpec defines the desired state of Pipeline
+type PipelineSpec struct {
+	// Stages in the order releases are promoted through them
+	Stages []PipelineStage `json:"stages,omitempty"`
+}
+
+// PipelineStatus defines the observed state of Pipeline
+type PipelineStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// Pipeline is the Schema for the pipelines API. It's cluster scoped as its
+// stages usually live in different namespaces.
+type Pipeline struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PipelineSpec   `json:"spec,omitempty"`
+	Status PipelineStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PipelineList contains a list of Pipeline
+type PipelineList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Pipeline `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Pipeline{}, &PipelineList{})
+}
@@ -0,0 +1,85 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReviewAppOverrides are applied over the parent's spec
+type ReviewAppOverrides struct {
+	// Image replaces the parent's image, the CLI can override it per review app
+	Image string `json:"image,omitempty"`
+	// Replicas replaces the parent's replica count
+	Replicas *int `json:"replicas,omitempty"`
+}
+
+// ReviewAppTemplateSpec defines the desired state of ReviewAppTemplate
+type ReviewAppTemplateSpec struct {
+	// App is the parent Application review apps are cloned from, it has to be
+	// in the template's namespace
+	App string `json:"app"`
+	// Overrides are applied over the parent's spec
+	Overrides ReviewAppOverrides `json:"overrides,omitempty"`
+	// IgnoreParentConfig starts review apps with an empty config instead of a
+	// copy of the parent's
+	IgnoreParentConfig bool `json:"ignoreParentConfig,omitempty"`
+	// Config is set over the copied config, e.g. to point at a review database
+	Config map[string]string `json:"config,omitempty"`
+	// Domain review apps get a host under, e.g. review.example.com routes
+	// app-pr-42.review.example.com to the review app. Routing is disabled
+	// when it's empty.
+	Domain string `json:"domain,omitempty"`
+	// TTL is how long review apps live before they're deleted, e.g. 72h,
+	// unset keeps them until they're destroyed
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// ReviewAppTemplateStatus defines the observed state of ReviewAppTemplate
+type ReviewAppTemplateStatus struct {
+	// Apps are the review apps created from the template
+	Apps []string `json:"apps,omitempty"`
+	// ExpiredApps are the review apps deleted by the last expiry
+	ExpiredApps []string `json:"expiredApps,omitempty"`
+	// LastExpiryTime is when review apps last expired
+	LastExpiryTime *metav1.Time `json:"lastExpiryTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// ReviewAppTemplate is the Schema for the reviewapptemplates API
+type ReviewAppTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReviewAppTemplateSpec   `json:"spec,omitempty"`
+	Status ReviewAppTemplateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReviewAppTemplateList contains a list of ReviewAppTemplate
+type ReviewAppTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReviewAppTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReviewAppTemplate{}, &ReviewAppTemplateList{})
+}
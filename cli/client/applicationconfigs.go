@@ -16,6 +16,7 @@ type ApplicationConfigInterface interface {
 	Create(applicationConfig *v1alpha1.ApplicationConfig) (*v1alpha1.ApplicationConfig, error)
 	Watch(opts metav1.ListOptions) (watch.Interface, error)
 	Update(applicationConfig *v1alpha1.ApplicationConfig) (*v1alpha1.ApplicationConfig, error)
+	Delete(name string, options *metav1.DeleteOptions) error
 }
 
 type applicationConfigClient struct {
@@ -85,3 +86,14 @@ func (c *applicationConfigClient) Update(applicationConfig *v1alpha1.Application
 
 	return applicationConfig, err
 }
+
+func (c *applicationConfigClient) Delete(name string, options *metav1.DeleteOptions) error {
+	return c.restClient.
+		Delete().
+		Namespace(c.ns).
+		Resource(appConfigResource).
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"github.com/mrferos/feisty/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+var appRevisionResource = "applicationrevisions"
+
+type ApplicationRevisionInterface interface {
+	List(opts metav1.ListOptions) (*v1alpha1.ApplicationRevisionList, error)
+	Get(name string, options metav1.GetOptions) (*v1alpha1.ApplicationRevision, error)
+	Create(applicationRevision *v1alpha1.ApplicationRevision) (*v1alpha1.ApplicationRevision, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Update(applicationRevision *v1alpha1.ApplicationRevision) (*v1alpha1.ApplicationRevision, error)
+	Delete(name string, options *metav1.DeleteOptions) error
+}
+
+type applicationRevisionClient struct {
+	restClient rest.Interface
+	ns         string
+}
+
+func (c *applicationRevisionClient) List(opts metav1.ListOptions) (*v1alpha1.ApplicationRevisionList, error) {
+	result := v1alpha1.ApplicationRevisionList{}
+	err := c.restClient.
+		Get().
+		Namespace(c.ns).
+		Resource(appRevisionResource).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+func (c *applicationRevisionClient) Get(name string, options metav1.GetOptions) (*v1alpha1.ApplicationRevision, error) {
+	result := v1alpha1.ApplicationRevision{}
+	err := c.restClient.
+		Get().
+		Namespace(c.ns).
+		Resource(appRevisionResource).
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+func (c *applicationRevisionClient) Create(applicationRevision *v1alpha1.ApplicationRevision) (*v1alpha1.ApplicationRevision, error) {
+	result := v1alpha1.ApplicationRevision{}
+	err := c.restClient.
+		Post().
+		Namespace(c.ns).
+		Resource(appRevisionResource).
+		Body(applicationRevision).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+func (c *applicationRevisionClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.restClient.
+		Get().
+		Namespace(c.ns).
+		Resource(appRevisionResource).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+func (c *applicationRevisionClient) Update(applicationRevision *v1alpha1.ApplicationRevision) (*v1alpha1.ApplicationRevision, error) {
+	err := c.restClient.
+		Put().
+		Namespace(c.ns).
+		Name(applicationRevision.Name).
+		Resource(appRevisionResource).
+		Body(applicationRevision).
+		Do().
+		Into(applicationRevision)
+
+	return applicationRevision, err
+}
+
+func (c *applicationRevisionClient) Delete(name string, options *metav1.DeleteOptions) error {
+	return c.restClient.
+		Delete().
+		Namespace(c.ns).
+		Resource(appRevisionResource).
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
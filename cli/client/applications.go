@@ -16,6 +16,7 @@ type ApplicationInterface interface {
 	Create(application *v1alpha1.Application) (*v1alpha1.Application, error)
 	Watch(opts metav1.ListOptions) (watch.Interface, error)
 	Update(application *v1alpha1.Application) (*v1alpha1.Application, error)
+	Delete(name string, options *metav1.DeleteOptions) error
 }
 
 type applicationClient struct {
@@ -85,3 +86,14 @@ func (c *applicationClient) Update(application *v1alpha1.Application) (*v1alpha1
 
 	return application, err
 }
+
+func (c *applicationClient) Delete(name string, options *metav1.DeleteOptions) error {
+	return c.restClient.
+		Delete().
+		Namespace(c.ns).
+		Resource(appResource).
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
@@ -11,6 +11,9 @@ import (
 type FeistyV1Alpha1Interface interface {
 	Applications(namespace string) ApplicationInterface
 	ApplicationConfigs(namespace string) ApplicationConfigInterface
+	ApplicationRevisions(namespace string) ApplicationRevisionInterface
+	Pipelines() PipelineInterface
+	ReviewAppTemplates(namespace string) ReviewAppTemplateInterface
 }
 
 type FeistyV1Alpha1Client struct {
@@ -48,3 +51,23 @@ func (c *FeistyV1Alpha1Client) ApplicationConfigs(namespace string) ApplicationC
 		ns:         namespace,
 	}
 }
+
+func (c *FeistyV1Alpha1Client) ApplicationRevisions(namespace string) ApplicationRevisionInterface {
+	return &applicationRevisionClient{
+		restClient: c.restClient,
+		ns:         namespace,
+	}
+}
+
+func (c *FeistyV1Alpha1Client) Pipelines() PipelineInterface {
+	return &pipelineClient{
+		restClient: c.restClient,
+	}
+}
+
+func (c *FeistyV1Alpha1Client) ReviewAppTemplates(namespace string) ReviewAppTemplateInterface {
+	return &reviewAppTemplateClient{
+		restClient: c.restClient,
+		ns:         namespace,
+	}
+}
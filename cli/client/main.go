@@ -3,13 +3,21 @@ package client
 import (
 	"flag"
 	"github.com/mrferos/feisty/api/v1alpha1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"os"
 	"path/filepath"
 )
 
-func GetFeistyClient() (*FeistyV1Alpha1Client, error) {
+var restConfig *rest.Config
+
+func getRestConfig() *rest.Config {
+	if restConfig != nil {
+		return restConfig
+	}
+
 	var kubeconfig *string
 	if home := homeDir(); home != "" {
 		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
@@ -25,6 +33,14 @@ func GetFeistyClient() (*FeistyV1Alpha1Client, error) {
 		panic(err.Error())
 	}
 
+	restConfig = config
+
+	return restConfig
+}
+
+func GetFeistyClient() (*FeistyV1Alpha1Client, error) {
+	config := getRestConfig()
+
 	_ = v1alpha1.AddToScheme(scheme.Scheme)
 
 	feistyV1Alpha1, err := NewForConfig(config)
@@ -35,6 +51,10 @@ func GetFeistyClient() (*FeistyV1Alpha1Client, error) {
 	return feistyV1Alpha1, nil
 }
 
+func GetKubernetesClient() (kubernetes.Interface, error) {
+	return kubernetes.NewForConfig(getRestConfig())
+}
+
 func homeDir() string {
 	if h := os.Getenv("HOME"); h != "" {
 		return h
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"github.com/mrferos/feisty/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+var pipelineResource = "pipelines"
+
+// PipelineInterface has no namespace as pipelines are cluster scoped
+type PipelineInterface interface {
+	List(opts metav1.ListOptions) (*v1alpha1.PipelineList, error)
+	Get(name string, options metav1.GetOptions) (*v1alpha1.Pipeline, error)
+}
+
+type pipelineClient struct {
+	restClient rest.Interface
+}
+
+func (c *pipelineClient) List(opts metav1.ListOptions) (*v1alpha1.PipelineList, error) {
+	result := v1alpha1.PipelineList{}
+	err := c.restClient.
+		Get().
+		Resource(pipelineResource).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+func (c *pipelineClient) Get(name string, options metav1.GetOptions) (*v1alpha1.Pipeline, error) {
+	result := v1alpha1.Pipeline{}
+	err := c.restClient.
+		Get().
+		Resource(pipelineResource).
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"github.com/mrferos/feisty/constants"
+	"k8s.io/apimachinery/pkg/labels"
+	"strings"
+)
+
+// AppPodSelector returns the label selector for the pods of an application,
+// optionally narrowed down to a process type and/or a revision (e.g. v5)
+func AppPodSelector(appName string, process string, revision string) string {
+	set := labels.Set{constants.AppLabel: appName}
+	if process != "" {
+		set[constants.ProcessTypeLabel] = process
+	}
+
+	if revision != "" {
+		if !strings.HasPrefix(revision, "v") {
+			revision = "v" + revision
+		}
+
+		set[constants.RevisionLabel] = revision
+	}
+
+	return labels.SelectorFromSet(set).String()
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"github.com/mrferos/feisty/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+var reviewAppTemplateResource = "reviewapptemplates"
+
+type ReviewAppTemplateInterface interface {
+	List(opts metav1.ListOptions) (*v1alpha1.ReviewAppTemplateList, error)
+	Get(name string, options metav1.GetOptions) (*v1alpha1.ReviewAppTemplate, error)
+}
+
+type reviewAppTemplateClient struct {
+	restClient rest.Interface
+	ns         string
+}
+
+func (c *reviewAppTemplateClient) List(opts metav1.ListOptions) (*v1alpha1.ReviewAppTemplateList, error) {
+	result := v1alpha1.ReviewAppTemplateList{}
+	err := c.restClient.
+		Get().
+		Namespace(c.ns).
+		Resource(reviewAppTemplateResource).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+func (c *reviewAppTemplateClient) Get(name string, options metav1.GetOptions) (*v1alpha1.ReviewAppTemplate, error) {
+	result := v1alpha1.ReviewAppTemplate{}
+	err := c.restClient.
+		Get().
+		Namespace(c.ns).
+		Resource(reviewAppTemplateResource).
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
@@ -9,12 +9,58 @@ import (
 	"os"
 )
 
+var appsCreateImage string
+var appsCreatePort int
+var appsCreateReplicas int
+var appsCreateConfigs []string
+
+func appsCreateCmdRun(args []string) error {
+	appName = args[0]
+	ns := getNamespace()
+
+	parsedConfigs, err := parseArgs(appsCreateConfigs)
+	if err != nil {
+		return fmt.Errorf("could not parse configs\n%v\n", err)
+	}
+
+	app := v1alpha1.Application{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      appName,
+			Namespace: ns,
+		},
+		Spec: v1alpha1.ApplicationSpec{
+			Image:    appsCreateImage,
+			Port:     appsCreatePort,
+			Replicas: appsCreateReplicas,
+		},
+	}
+
+	appConfig := newAppConfig(ns)
+	appConfig.Spec.KeyValuePairs = parsedConfigs
+
+	annotateChange(&app.ObjectMeta, "")
+	annotateChange(&appConfig.ObjectMeta, "")
+
+	if _, err := feistyClient.Applications(ns).Create(&app); err != nil {
+		return fmt.Errorf("There was an error creating the application: \n%v\n", err)
+	}
+
+	if _, err := feistyClient.ApplicationConfigs(ns).Create(appConfig); err != nil {
+		return fmt.Errorf("The application %s in namespace %s was created but its config was not: \n%v\n", app.Name, app.Namespace, err)
+	}
+
+	fmt.Printf("The application %s in namespace %s was created!\n", app.Name, app.Namespace)
+
+	return nil
+}
+
 var appsCreateCmd = &cobra.Command{
 	Use:   "apps:create",
 	Short: "Create application",
-	Long: `Create a new application. Example:
+	Long: `Create a new application along with its config. Example:
 
 feisty apps:create cool-app
+feisty apps:create cool-app --image nginxdemos/hello:plain-text --port 80 --replicas 2 -c HOST=cool-host
 `,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
@@ -24,24 +70,18 @@ feisty apps:create cool-app
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		ns := getNamespace()
-		app := v1alpha1.Application{
-			ObjectMeta: v1.ObjectMeta{
-				Name:      args[0],
-				Namespace: ns,
-			},
-		}
-
-		if _, err := feistyClient.Applications(ns).Create(&app); err != nil {
-			fmt.Printf("There was an error creating the application: \n")
-			fmt.Printf("%v\n", err)
+		if err := appsCreateCmdRun(args); err != nil {
+			fmt.Print(err)
 			os.Exit(1)
-		} else {
-			fmt.Printf("The application %s in namespace %s was created!\n", app.Name, app.Namespace)
 		}
 	},
 }
 
 func init() {
+	appsCreateCmd.Flags().StringVar(&appsCreateImage, "image", "", "the image to deploy")
+	appsCreateCmd.Flags().IntVar(&appsCreatePort, "port", 0, "the application's exposed port")
+	appsCreateCmd.Flags().IntVar(&appsCreateReplicas, "replicas", 0, "how many instances of the application should be running")
+	appsCreateCmd.Flags().StringArrayVarP(&appsCreateConfigs, "config", "c", []string{}, "initial config as KEY=value, may be repeated")
+	addChangeFlags(appsCreateCmd)
 	rootCmd.AddCommand(appsCreateCmd)
 }
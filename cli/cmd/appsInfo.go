@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// findCondition returns the application condition of the given type, if any
+func findCondition(app v1alpha1.Application, conditionType v1alpha1.ApplicationConditionType) *v1alpha1.ApplicationCondition {
+	for i, condition := range app.Status.Conditions {
+		if condition.Type == conditionType {
+			return &app.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func appsInfoCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	var hosts []string
+	for _, domain := range app.Spec.Domains {
+		hosts = append(hosts, domain.Host)
+	}
+
+	strategy := app.Spec.Strategy
+	if strategy == "" {
+		strategy = v1alpha1.RollingStrategy
+	}
+
+	release := ""
+	if current, _ := revisions.CurrentRevisionNumber(*app); current > 0 {
+		release = "v" + strconv.Itoa(current)
+	}
+
+	maintenance := onOff(app.Spec.Maintenance)
+	if condition := findCondition(*app, v1alpha1.ApplicationMaintenance); condition != nil && condition.Status == corev1.ConditionTrue {
+		maintenance += " since " + condition.LastTransitionTime.Local().Format("2006-01-02 15:04:05")
+	} else if app.Spec.Maintenance {
+		maintenance += " (not applied yet)"
+	}
+
+	replicas := strconv.Itoa(app.Spec.Replicas)
+	if revisions.Stopped(app.Spec) {
+		replicas += ", stopped (" + formatFormation(app.Spec.StoppedFormation) + " on start)"
+	}
+
+	sleep := "off"
+	if app.Spec.SleepAfter != nil {
+		sleep = "after " + app.Spec.SleepAfter.Duration.String()
+		if s := app.Status.Sleep; s != nil && s.Asleep && s.SleepTime != nil {
+			sleep += ", asleep since " + s.SleepTime.Local().Format("2006-01-02 15:04:05")
+		} else if s != nil && s.LastActivityTime != nil {
+			sleep += ", last request at " + s.LastActivityTime.Local().Format("2006-01-02 15:04:05")
+		}
+	}
+
+	headers := []string{"KEY", "VALUE"}
+	data := [][]string{
+		{},
+		{"Name", app.Name},
+		{"Namespace", app.Namespace},
+		{"Created", app.CreationTimestamp.Format("2006-01-02 15:04:05")},
+		{"Image", app.Spec.Image},
+		{"Replicas", replicas},
+		{"Port", strconv.Itoa(app.Spec.Port)},
+		{"Strategy", string(strategy)},
+		{"Routing", onOff(app.Spec.RoutingEnabled)},
+		{"Domains", strings.Join(hosts, ", ")},
+		{"Auto rollback", onOff(app.Spec.AutoRollback)},
+		{"Maintenance", maintenance},
+		{"Sleep", sleep},
+		{"Release", release},
+		{"Config secret", app.Spec.AppConfigRef},
+	}
+
+	for _, condition := range app.Status.Conditions {
+		value := string(condition.Status)
+		if condition.Message != "" {
+			value += ": " + condition.Message
+		}
+
+		data = append(data, []string{"Condition " + string(condition.Type), value})
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var appsInfoCmd = &cobra.Command{
+	Use:   "apps:info",
+	Short: "Show application details",
+	Long: `Show the settings and state of an application, including whether it's in
+maintenance. Example:
+
+feisty apps:info -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := appsInfoCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	appsInfoCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(appsInfoCmd)
+}
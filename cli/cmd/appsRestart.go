@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/mrferos/feisty/revisions"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"os"
@@ -19,11 +20,18 @@ func appsRestartCmdRun(args []string) error {
 	currentTime := time.Now()
 	app.Spec.RestartTime = currentTime.Format("2006-01-02 15:04:05.000000000")
 
-	if _, err := feistyClient.Applications(ns).Update(app); err != nil {
+	annotateChange(&app.ObjectMeta, revisions.CauseRestart)
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
 		return fmt.Errorf("there was an error restarting %s\n%v", app.Name, err)
 	}
 
-	fmt.Printf("%s in %s was restarted", app.Name, app.Namespace)
+	fmt.Printf("%s in %s was restarted\n", app.Name, app.Namespace)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
 
 	return nil
 }
@@ -34,6 +42,7 @@ var appsRestartCmd = &cobra.Command{
 	Long: `Issue a restart of all running application pods. Example:
 
 feisty apps:restart -a application-sample
+feisty apps:restart --wait -a application-sample
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := appsRestartCmdRun(args); err != nil {
@@ -45,5 +54,7 @@ feisty apps:restart -a application-sample
 
 func init() {
 	appsRestartCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(appsRestartCmd)
+	addChangeFlags(appsRestartCmd)
 	rootCmd.AddCommand(appsRestartCmd)
 }
@@ -3,10 +3,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"os"
 	"strconv"
+	"time"
 )
 
 func appSetCmdRun(args []string) error {
@@ -32,7 +35,9 @@ func appSetCmdRun(args []string) error {
 			if err != nil {
 				return fmt.Errorf("could not parse replicas: %s", val)
 			} else {
+				// scaling a stopped application starts it
 				app.Spec.Replicas = replicas
+				app.Spec.StoppedFormation = nil
 			}
 		case "port":
 			port, err := strconv.Atoi(val)
@@ -41,6 +46,76 @@ func appSetCmdRun(args []string) error {
 			} else {
 				app.Spec.Port = port
 			}
+		case "autoRollback":
+			if val == "true" {
+				app.Spec.AutoRollback = true
+			} else if val == "false" {
+				app.Spec.AutoRollback = false
+			} else {
+				return fmt.Errorf("could not parse auto rollback; %s", val)
+			}
+		case "maxSurge", "maxUnavailable":
+			value := intstr.Parse(val)
+			if key == "maxSurge" {
+				app.Spec.MaxSurge = &value
+			} else {
+				app.Spec.MaxUnavailable = &value
+			}
+		case "minReadySeconds":
+			seconds, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("could not parse min ready seconds: %s", val)
+			} else {
+				app.Spec.MinReadySeconds = seconds
+			}
+		case "progressDeadlineSeconds":
+			seconds, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("could not parse progress deadline seconds: %s", val)
+			} else {
+				app.Spec.ProgressDeadlineSeconds = &seconds
+			}
+		case "sleepAfter":
+			sleepAfter, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("could not parse sleep after: %s", val)
+			} else if sleepAfter <= 0 {
+				app.Spec.SleepAfter = nil
+			} else {
+				app.Spec.SleepAfter = &v1.Duration{Duration: sleepAfter}
+			}
+		case "nodeSelector":
+			selector, err := parseNodeSelector(val)
+			if err != nil {
+				return err
+			}
+
+			schedulingOf(app).NodeSelector = selector
+		case "tolerations":
+			tolerations, err := parseTolerations(val)
+			if err != nil {
+				return err
+			}
+
+			schedulingOf(app).Tolerations = tolerations
+		case "topologySpread":
+			schedulingOf(app).TopologySpread = parseTopologySpread(val)
+		case "spread":
+			if val == "true" || val == "false" {
+				spread := val == "true"
+				schedulingOf(app).Spread = &spread
+			} else if val == "" {
+				schedulingOf(app).Spread = nil
+			} else {
+				return fmt.Errorf("could not parse spread; %s", val)
+			}
+		case "strategy":
+			switch v1alpha1.DeploymentStrategyType(val) {
+			case v1alpha1.RollingStrategy, v1alpha1.BlueGreenStrategy, v1alpha1.CanaryStrategy:
+				app.Spec.Strategy = v1alpha1.DeploymentStrategyType(val)
+			default:
+				return fmt.Errorf("unknown strategy %s, expected rolling, bluegreen or canary", val)
+			}
 		case "routingEnabled":
 			if val == "true" {
 				app.Spec.RoutingEnabled = true
@@ -52,11 +127,18 @@ func appSetCmdRun(args []string) error {
 		}
 	}
 
-	if _, err := feistyClient.Applications(ns).Update(app); err != nil {
+	annotateChange(&app.ObjectMeta, "")
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
 		return fmt.Errorf("there was an error updating %s\n%v", app.Name, err)
 	}
 
-	fmt.Printf("%s in %s was updated", app.Name, app.Namespace)
+	fmt.Printf("%s in %s was updated\n", app.Name, app.Namespace)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
 
 	return nil
 }
@@ -67,12 +149,29 @@ var appsSetCmd = &cobra.Command{
 	Long: `Set application options. Example:
 
 feisty apps:set image=nginx/helloworld -a application-sample
+feisty apps:set image=nginx/helloworld --wait --timeout 10m -a application-sample
 
 Supported options:
 	* image - the image to deploy
 	* replicas - how many instances of the application should be running
 	* port - the application's exposed port
 	* routingEnabled - true/false value to manage an ingress for the application
+	* autoRollback - true/false value to roll back to the last good release when a rollout fails
+	* maxSurge - how many pods (e.g. 1) or what percentage of replicas (e.g. 25%) may be added during a rollout
+	* maxUnavailable - how many pods or what percentage of replicas may be unavailable during a rollout
+	* minReadySeconds - how long a new pod must be ready before it counts as available
+	* progressDeadlineSeconds - how long a rollout may go without progress before it fails
+	* strategy - how new releases are rolled out: rolling (the default), bluegreen or canary
+	* sleepAfter - scale the application to zero after it got no requests for this long (e.g. 30m), 0 never sleeps.
+	  All of the application's requests then go through the wake-up proxy, not only the ones waking it up
+	* nodeSelector - node labels the pods must run on, e.g. pool=production,disk=ssd
+	* tolerations - node taints the pods tolerate, e.g. pool=production:NoSchedule,gpu:NoExecute
+	* topologySpread - node labels to spread the pods evenly across, e.g. topology.kubernetes.io/zone
+	* spread - true/false value to prefer running the pods on different nodes, empty uses the namespace default
+
+Options left empty (e.g. nodeSelector=) are cleared. The scheduling defaults of the namespace,
+set with its paas.feisty.dev/scheduling annotation, are added to nodeSelector and tolerations
+and used for the other scheduling options when they're unset.
 `,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
@@ -91,5 +190,7 @@ Supported options:
 
 func init() {
 	appsSetCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(appsSetCmd)
+	addChangeFlags(appsSetCmd)
 	rootCmd.AddCommand(appsSetCmd)
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func appsStartCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	if !revisions.Stopped(app.Spec) {
+		fmt.Printf("%s in %s isn't stopped\n", app.Name, app.Namespace)
+		return nil
+	}
+
+	formation := formatFormation(app.Spec.StoppedFormation)
+	revisions.Start(&app.Spec)
+	annotateChange(&app.ObjectMeta, "")
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error starting %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s in %s was started with %s\n", app.Name, app.Namespace, formation)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var appsStartCmd = &cobra.Command{
+	Use:   "apps:start",
+	Short: "Start a stopped application",
+	Long: `Bring back the replicas each process type of an application ran before it was
+stopped. Example:
+
+feisty apps:start -a application-sample
+feisty apps:start --wait -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := appsStartCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	appsStartCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(appsStartCmd)
+	addChangeFlags(appsStartCmd)
+	rootCmd.AddCommand(appsStartCmd)
+}
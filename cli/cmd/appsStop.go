@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formatFormation lists the replicas of each process type, e.g. web=2
+func formatFormation(formation map[string]int) string {
+	var parts []string
+	for processType, replicas := range formation {
+		parts = append(parts, processType+"="+strconv.Itoa(replicas))
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, ", ")
+}
+
+func appsStopCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	if revisions.Stopped(app.Spec) {
+		fmt.Printf("%s in %s is already stopped\n", app.Name, app.Namespace)
+		return nil
+	}
+
+	revisions.Stop(&app.Spec)
+	annotateChange(&app.ObjectMeta, "")
+
+	if _, err := feistyClient.Applications(ns).Update(app); err != nil {
+		return fmt.Errorf("there was an error stopping %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s in %s was stopped, apps:start brings back %s\n", app.Name, app.Namespace, formatFormation(app.Spec.StoppedFormation))
+
+	return nil
+}
+
+var appsStopCmd = &cobra.Command{
+	Use:   "apps:stop",
+	Short: "Stop an application",
+	Long: `Scale every process type of an application to zero, its ingress traffic gets
+an unavailable page until it's started again. Configuration and releases are
+kept. Example:
+
+feisty apps:stop -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := appsStopCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	appsStopCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addChangeFlags(appsStopCmd)
+	rootCmd.AddCommand(appsStopCmd)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os/user"
+)
+
+var changeMessage string
+
+func addChangeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&changeMessage, "message", "m", "", "describe why the change was made, it's recorded on the release")
+}
+
+// changeAuthor is who the change is attributed to when the change author
+// webhook isn't enabled; set author in the feisty config to override it
+func changeAuthor() string {
+	if author := viper.GetString("author"); author != "" {
+		return author
+	}
+
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+
+	return ""
+}
+
+// annotateChange records who is making a change and why, so it ends up on the
+// release the change produces. An empty cause lets the controller work it out.
+func annotateChange(meta *v1.ObjectMeta, cause string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+
+	values := map[string]string{
+		revisions.ChangeAuthorAnnotation:       changeAuthor(),
+		revisions.ChangeCauseAnnotation:        cause,
+		revisions.ChangeMessageAnnotation:      changeMessage,
+		revisions.ChangePromotedFromAnnotation: "",
+		revisions.ChangeIDAnnotation:           revisions.NewChangeID(),
+	}
+
+	for key, val := range values {
+		if val == "" {
+			delete(meta.Annotations, key)
+		} else {
+			meta.Annotations[key] = val
+		}
+	}
+}
+
+// annotatePromotion is annotateChange for a promotion of the image of another
+// release, given as namespace/revision
+func annotatePromotion(meta *v1.ObjectMeta, promotedFrom string) {
+	annotateChange(meta, revisions.CausePromote)
+	meta.Annotations[revisions.ChangePromotedFromAnnotation] = promotedFrom
+}
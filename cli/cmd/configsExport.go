@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/cli/configfile"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strings"
+)
+
+var configsExportFormat string
+
+func configsExportCmdRun(args []string) error {
+	ns := getNamespace()
+
+	appConfig, err := feistyClient.ApplicationConfigs(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("There was an error getting configurations for %s\n%v\n", appName, err)
+	}
+
+	out, err := configfile.Format(appConfig.Spec.KeyValuePairs, configsExportFormat)
+	if err != nil {
+		return fmt.Errorf("could not export configs for %s\n%v\n", appName, err)
+	}
+
+	fmt.Print(string(out))
+
+	return nil
+}
+
+var configsExportCmd = &cobra.Command{
+	Use:   "configs:export",
+	Short: "Export application configs",
+	Long: `Export application configs as dotenv, JSON, YAML or shell exports. Example:
+
+feisty configs:export -a application-sample > .env
+feisty configs:export --format shell -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := configsExportCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configsExportCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	configsExportCmd.Flags().StringVar(&configsExportFormat, "format", configfile.FormatDotenv, "output format: "+strings.Join(configfile.Formats, ", "))
+	rootCmd.AddCommand(configsExportCmd)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/cli/configfile"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+var configsImportFormat string
+var configsImportReplace bool
+var configsImportYes bool
+
+type configChange struct {
+	op  string
+	key string
+}
+
+func diffConfigs(current map[string]string, incoming map[string]string, replace bool) []configChange {
+	var changes []configChange
+	for k, v := range incoming {
+		if currentVal, ok := current[k]; !ok {
+			changes = append(changes, configChange{"+", k})
+		} else if currentVal != v {
+			changes = append(changes, configChange{"~", k})
+		}
+	}
+
+	if replace {
+		for k := range current {
+			if _, ok := incoming[k]; !ok {
+				changes = append(changes, configChange{"-", k})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].key < changes[j].key
+	})
+
+	return changes
+}
+
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+func configsImportCmdRun(args []string) error {
+	ns := getNamespace()
+
+	format := configsImportFormat
+	if format == "" {
+		format = configfile.FormatFromFilename(args[0])
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("could not read %s\n%v\n", args[0], err)
+	}
+
+	incoming, err := configfile.Parse(data, format)
+	if err != nil {
+		return fmt.Errorf("could not parse %s\n%v\n", args[0], err)
+	}
+
+	appConfig, exists, err := getOrNewAppConfig(ns)
+	if err != nil {
+		return fmt.Errorf("could not load config %s\n%v\n", appName, err)
+	}
+
+	changes := diffConfigs(appConfig.Spec.KeyValuePairs, incoming, configsImportReplace)
+	if len(changes) == 0 {
+		fmt.Printf("%s in %s is already up to date\n", appConfig.Name, appConfig.Namespace)
+		return nil
+	}
+
+	fmt.Printf("Changes to %s in %s:\n", appConfig.Name, appConfig.Namespace)
+	for _, change := range changes {
+		fmt.Printf("  %s %s\n", change.op, change.key)
+	}
+
+	if !configsImportYes && !confirm("Apply these changes?") {
+		return errors.New("import aborted\n")
+	}
+
+	for _, change := range changes {
+		if change.op == "-" {
+			delete(appConfig.Spec.KeyValuePairs, change.key)
+		} else {
+			appConfig.Spec.KeyValuePairs[change.key] = incoming[change.key]
+		}
+	}
+
+	annotateChange(&appConfig.ObjectMeta, "")
+
+	if err := saveAppConfig(ns, appConfig, exists); err != nil {
+		return fmt.Errorf("there was an error updating %s\n%v", appConfig.Name, err)
+	}
+
+	fmt.Printf("%s in %s was updated", appConfig.Name, appConfig.Namespace)
+
+	return nil
+}
+
+var configsImportCmd = &cobra.Command{
+	Use:   "configs:import",
+	Short: "Import application configs from a file",
+	Long: `Import application configs from a dotenv, JSON or YAML file. A preview
+of the added (+), changed (~) and removed (-) keys is shown before anything
+is applied. Example:
+
+feisty configs:import .env -a application-sample
+feisty configs:import config.json --replace -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a file to import is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := configsImportCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configsImportCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	configsImportCmd.Flags().StringVar(&configsImportFormat, "format", "", "file format: "+strings.Join(configfile.Formats, ", ")+" (defaults to the file extension)")
+	configsImportCmd.Flags().BoolVar(&configsImportReplace, "replace", false, "remove configs that are not in the file")
+	configsImportCmd.Flags().BoolVarP(&configsImportYes, "yes", "y", false, "apply without asking for confirmation")
+	addChangeFlags(configsImportCmd)
+	rootCmd.AddCommand(configsImportCmd)
+}
@@ -3,15 +3,59 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"os"
 )
 
+func newAppConfig(ns string) *v1alpha1.ApplicationConfig {
+	return &v1alpha1.ApplicationConfig{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      appName,
+			Namespace: ns,
+		},
+		Spec: v1alpha1.ApplicationConfigSpec{
+			KeyValuePairs: map[string]string{},
+		},
+	}
+}
+
+// getOrNewAppConfig loads the config for appName, returning a new unsaved
+// one when it doesn't exist yet. The bool is true when the config exists.
+func getOrNewAppConfig(ns string) (*v1alpha1.ApplicationConfig, bool, error) {
+	appConfig, err := feistyClient.ApplicationConfigs(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, false, err
+		}
+
+		return newAppConfig(ns), false, nil
+	}
+
+	if appConfig.Spec.KeyValuePairs == nil {
+		appConfig.Spec.KeyValuePairs = map[string]string{}
+	}
+
+	return appConfig, true, nil
+}
+
+func saveAppConfig(ns string, appConfig *v1alpha1.ApplicationConfig, exists bool) error {
+	var err error
+	if exists {
+		_, err = feistyClient.ApplicationConfigs(ns).Update(appConfig)
+	} else {
+		_, err = feistyClient.ApplicationConfigs(ns).Create(appConfig)
+	}
+
+	return err
+}
+
 func configsSetCmdRun(args []string) error {
 	ns := getNamespace()
 
-	appConfig, err := feistyClient.ApplicationConfigs(ns).Get(appName, v1.GetOptions{})
+	appConfig, exists, err := getOrNewAppConfig(ns)
 	if err != nil {
 		return fmt.Errorf("could not load config %s\n%v\n", appName, err)
 
@@ -22,15 +66,38 @@ func configsSetCmdRun(args []string) error {
 		return fmt.Errorf("could not parse configs")
 	}
 
+	// the config secret only changes when a value does, so that's the only
+	// time there's a rollout to wait for
+	changed := !exists
 	for key, val := range parsedArgs {
+		if currentVal, ok := appConfig.Spec.KeyValuePairs[key]; !ok || currentVal != val {
+			changed = true
+		}
+
 		appConfig.Spec.KeyValuePairs[key] = val
 	}
 
-	if _, err := feistyClient.ApplicationConfigs(ns).Update(appConfig); err != nil {
+	previousRef := ""
+	if waitForRollout {
+		app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+		}
+
+		previousRef = app.Spec.AppConfigRef
+	}
+
+	annotateChange(&appConfig.ObjectMeta, "")
+
+	if err := saveAppConfig(ns, appConfig, exists); err != nil {
 		return fmt.Errorf("there was an error updating %s\n%v", appConfig.Name, err)
 	}
 
-	fmt.Printf("%s in %s was updated", appConfig.Name, appConfig.Namespace)
+	fmt.Printf("%s in %s was updated\n", appConfig.Name, appConfig.Namespace)
+
+	if waitForRollout && changed {
+		return waitForConfig(ns, previousRef)
+	}
 
 	return nil
 }
@@ -38,9 +105,11 @@ func configsSetCmdRun(args []string) error {
 var configsSetCmd = &cobra.Command{
 	Use:   "configs:set",
 	Short: "Set application configs",
-	Long: `Set application configs. Example:
+	Long: `Set application configs. The config is created if the application
+doesn't have one yet. Example:
 
 feisty configs:set ONE=value TWO=more POSSIBLE=values -a application-sample
+feisty configs:set ONE=value --wait -a application-sample
 
 `,
 	Args: func(cmd *cobra.Command, args []string) error {
@@ -60,5 +129,7 @@ feisty configs:set ONE=value TWO=more POSSIBLE=values -a application-sample
 
 func init() {
 	configsSetCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(configsSetCmd)
+	addChangeFlags(configsSetCmd)
 	rootCmd.AddCommand(configsSetCmd)
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrferos/feisty/cli/logs"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var logsTail bool
+var logsLines int64
+var logsSince time.Duration
+var logsProcess string
+var logsRevision string
+var logsNoColor bool
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func logsCmdRun(args []string) error {
+	ns := getNamespace()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	streamer := logs.Streamer{
+		Client:    kubeClient,
+		Namespace: ns,
+		Out:       os.Stdout,
+	}
+
+	opts := logs.Options{
+		Follow:   logsTail,
+		Since:    logsSince,
+		Lines:    logsLines,
+		Process:  logsProcess,
+		Revision: logsRevision,
+		Color:    !logsNoColor && isTerminal(os.Stdout),
+	}
+
+	if err := streamer.Stream(ctx, appName, opts); err != nil {
+		return fmt.Errorf("could not get logs for %s\n%v\n", appName, err)
+	}
+
+	return nil
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show application logs",
+	Long: `Show the logs of every pod of an application, prefixed with the pod and
+container each line came from. Example:
+
+feisty logs -a application-sample
+feisty logs --tail --process web --since 1h -a application-sample
+feisty logs --revision v5 -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := logsCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	logsCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	logsCmd.Flags().BoolVarP(&logsTail, "tail", "t", false, "keep streaming logs, including from pods started later")
+	logsCmd.Flags().Int64Var(&logsLines, "lines", 100, "number of lines to show per container, -1 for all")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "only show logs newer than a duration, e.g. 1h")
+	logsCmd.Flags().StringVar(&logsProcess, "process", "", "only show logs for a process type, e.g. web")
+	logsCmd.Flags().StringVar(&logsRevision, "revision", "", "only show logs for pods from a revision, e.g. v5")
+	logsCmd.Flags().BoolVar(&logsNoColor, "no-color", false, "disable coloured prefixes")
+	rootCmd.AddCommand(logsCmd)
+}
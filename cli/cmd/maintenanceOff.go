@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func maintenanceOffCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	if !app.Spec.Maintenance {
+		fmt.Printf("%s in %s isn't in maintenance\n", app.Name, app.Namespace)
+		return nil
+	}
+
+	app.Spec.Maintenance = false
+	annotateChange(&app.ObjectMeta, "")
+
+	if _, err := feistyClient.Applications(ns).Update(app); err != nil {
+		return fmt.Errorf("there was an error turning maintenance off for %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s in %s is out of maintenance\n", app.Name, app.Namespace)
+
+	return nil
+}
+
+var maintenanceOffCmd = &cobra.Command{
+	Use:   "maintenance:off",
+	Short: "Take an application out of maintenance",
+	Long: `Send the ingress traffic of an application back to it. Example:
+
+feisty maintenance:off -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := maintenanceOffCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	maintenanceOffCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addChangeFlags(maintenanceOffCmd)
+	rootCmd.AddCommand(maintenanceOffCmd)
+}
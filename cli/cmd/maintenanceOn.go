@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+var maintenancePageFile string
+
+func maintenanceOnCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	if maintenancePageFile != "" {
+		page, err := ioutil.ReadFile(maintenancePageFile)
+		if err != nil {
+			return fmt.Errorf("could not read the maintenance page\n%v\n", err)
+		}
+
+		app.Spec.MaintenancePage = string(page)
+	}
+
+	if app.Spec.Maintenance && maintenancePageFile == "" {
+		fmt.Printf("%s in %s is already in maintenance\n", app.Name, app.Namespace)
+		return nil
+	}
+
+	app.Spec.Maintenance = true
+	annotateChange(&app.ObjectMeta, "")
+
+	if _, err := feistyClient.Applications(ns).Update(app); err != nil {
+		return fmt.Errorf("there was an error turning maintenance on for %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s in %s is in maintenance, its ingress traffic gets the maintenance page\n", app.Name, app.Namespace)
+
+	return nil
+}
+
+var maintenanceOnCmd = &cobra.Command{
+	Use:   "maintenance:on",
+	Short: "Put an application in maintenance",
+	Long: `Send the ingress traffic of an application to a maintenance page answering
+503, the application keeps running so workers and migrations can carry on.
+Example:
+
+feisty maintenance:on -a application-sample
+feisty maintenance:on --page maintenance.html -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := maintenanceOnCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	maintenanceOnCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	maintenanceOnCmd.Flags().StringVar(&maintenancePageFile, "page", "", "an HTML file to show instead of the generic maintenance page")
+	addChangeFlags(maintenanceOnCmd)
+	rootCmd.AddCommand(maintenanceOnCmd)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strings"
+)
+
+var pipelinesPromotePipeline string
+var pipelinesPromoteTo string
+
+// findPromotion finds the stage appName runs in ns and the stage it promotes
+// to, which is the next one unless toStage names another
+func findPromotion(pipelines []v1alpha1.Pipeline, ns string, toStage string) (*v1alpha1.PipelineStage, *v1alpha1.PipelineStage, error) {
+	var matches []string
+	var source, target *v1alpha1.PipelineStage
+	for _, pipeline := range pipelines {
+		stages := pipeline.Spec.Stages
+		for i := range stages {
+			if stages[i].App != appName || stages[i].StageNamespace() != ns {
+				continue
+			}
+
+			matches = append(matches, pipeline.Name)
+			source = &stages[i]
+			target = nil
+			for j := range stages {
+				if (toStage == "" && j == i+1) || (toStage != "" && stages[j].Name == toStage) {
+					target = &stages[j]
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		return nil, nil, fmt.Errorf("%s isn't a stage of any pipeline", appName)
+	case len(matches) > 1:
+		return nil, nil, fmt.Errorf("%s is a stage of several pipelines (%s), pick one with --pipeline", appName, strings.Join(matches, ", "))
+	case target == nil && toStage != "":
+		return nil, nil, fmt.Errorf("pipeline %s has no stage %s", matches[0], toStage)
+	case target == nil:
+		return nil, nil, fmt.Errorf("%s is the last stage of pipeline %s, there's nothing to promote to", source.Name, matches[0])
+	}
+
+	return source, target, nil
+}
+
+func pipelinesPromoteCmdRun(args []string) error {
+	ns := getNamespace()
+
+	var pipelines []v1alpha1.Pipeline
+	if pipelinesPromotePipeline != "" {
+		pipeline, err := feistyClient.Pipelines().Get(pipelinesPromotePipeline, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not load pipeline %s\n%v\n", pipelinesPromotePipeline, err)
+		}
+
+		pipelines = append(pipelines, *pipeline)
+	} else {
+		pipelineList, err := feistyClient.Pipelines().List(v1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("could not list pipelines\n%v\n", err)
+		}
+
+		pipelines = pipelineList.Items
+	}
+
+	source, target, err := findPromotion(pipelines, ns, pipelinesPromoteTo)
+	if err != nil {
+		return fmt.Errorf("%v\n", err)
+	}
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	current, err := revisions.CurrentRevisionNumber(*app)
+	if err != nil || current == 0 {
+		return fmt.Errorf("%s has no release to promote\n", appName)
+	}
+
+	rev, err := feistyClient.ApplicationRevisions(ns).Get(revisions.RevisionName(appName, current), v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load the current release of %s\n%v\n", appName, err)
+	}
+
+	if rev.Status.Phase == v1alpha1.RevisionFailed {
+		return fmt.Errorf("the rollout of %s failed, it won't be promoted\n", rev.Name)
+	}
+
+	// a tag can be moved after the fact, only the digest says what was tested
+	if rev.Spec.ImageDigest == "" {
+		return fmt.Errorf("the image of %s hasn't been resolved to a digest yet, wait for it to roll out\n", rev.Name)
+	}
+
+	targetNs := target.StageNamespace()
+	targetApp, err := feistyClient.Applications(targetNs).Get(target.App, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s of stage %s\n%v\n", target.App, target.Name, err)
+	}
+
+	image := revisions.PinnedImage(rev.Spec)
+	if targetApp.Spec.Image == image {
+		fmt.Printf("%s in %s already runs %s\n", targetApp.Name, targetNs, image)
+		return nil
+	}
+
+	// only the image moves, the target keeps its own config and settings
+	targetApp.Spec.Image = image
+	annotatePromotion(&targetApp.ObjectMeta, ns+"/"+rev.Name)
+
+	updated, err := feistyClient.Applications(targetNs).Update(targetApp)
+	if err != nil {
+		return fmt.Errorf("there was an error promoting to %s\n%v", targetApp.Name, err)
+	}
+
+	fmt.Printf("Promoted %s of %s (%s) to %s in %s (%s)\n", rev.Name, source.Name, image, targetApp.Name, targetNs, target.Name)
+
+	if waitForRollout {
+		return waitForApp(targetNs, updated)
+	}
+
+	return nil
+}
+
+var pipelinesPromoteCmd = &cobra.Command{
+	Use:   "pipelines:promote",
+	Short: "Promote the current release to the next pipeline stage",
+	Long: `Deploy the image of the application's current release to the application of
+the next stage of its pipeline. The image is deployed by digest so the next
+stage runs exactly what was tested, the target keeps its own config. Example:
+
+feisty pipelines:promote -a application-sample-staging
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pipelinesPromoteCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	pipelinesPromoteCmd.Flags().StringVarP(&appName, "app name", "a", "", "the application to promote from")
+	pipelinesPromoteCmd.Flags().StringVar(&pipelinesPromotePipeline, "pipeline", "", "the pipeline to promote through, when the app is in several")
+	pipelinesPromoteCmd.Flags().StringVar(&pipelinesPromoteTo, "to", "", "the stage to promote to (defaults to the next stage)")
+	addWaitFlags(pipelinesPromoteCmd)
+	addChangeFlags(pipelinesPromoteCmd)
+	rootCmd.AddCommand(pipelinesPromoteCmd)
+}
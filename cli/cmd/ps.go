@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/cli/client"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/constants"
+	"github.com/spf13/cobra"
+	v12 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type process struct {
+	// name is the heroku style name of the pod, e.g. web.1
+	name string
+	pod  v12.Pod
+}
+
+// listProcesses returns the pods of the app named by process type and their
+// position within it, oldest first
+func listProcesses(ns string) ([]process, error) {
+	pods, err := kubeClient.CoreV1().Pods(ns).List(v1.ListOptions{
+		LabelSelector: client.AppPodSelector(appName, "", ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool {
+		a := pods.Items[i].CreationTimestamp
+		b := pods.Items[j].CreationTimestamp
+		if a.Equal(&b) {
+			return pods.Items[i].Name < pods.Items[j].Name
+		}
+
+		return a.Before(&b)
+	})
+
+	counts := map[string]int{}
+	var processes []process
+	for _, pod := range pods.Items {
+		processType := pod.Labels[constants.ProcessTypeLabel]
+		if processType == "" {
+			processType = constants.DefaultProcessType
+		}
+
+		counts[processType]++
+		processes = append(processes, process{
+			name: processType + "." + strconv.Itoa(counts[processType]),
+			pod:  pod,
+		})
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processTypeOf(processes[i].name) < processTypeOf(processes[j].name)
+	})
+
+	return processes, nil
+}
+
+func processTypeOf(name string) string {
+	return strings.SplitN(name, ".", 2)[0]
+}
+
+// findProcesses matches a process name (web.1), a process type (web) or a pod name
+func findProcesses(processes []process, target string) []process {
+	var found []process
+	for _, p := range processes {
+		if p.name == target || p.pod.Name == target || processTypeOf(p.name) == target {
+			found = append(found, p)
+		}
+	}
+
+	return found
+}
+
+func podState(pod v12.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "terminating"
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return status.State.Waiting.Reason
+		}
+
+		if status.State.Terminated != nil && status.State.Terminated.Reason != "" {
+			return status.State.Terminated.Reason
+		}
+	}
+
+	if pod.Status.Phase == v12.PodRunning {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == v12.PodReady && condition.Status != v12.ConditionTrue {
+				return "starting"
+			}
+		}
+
+		return "up"
+	}
+
+	return strings.ToLower(string(pod.Status.Phase))
+}
+
+func podRestarts(pod v12.Pod) int32 {
+	restarts := int32(0)
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+
+	return restarts
+}
+
+func podLastTermination(pod v12.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			return fmt.Sprintf("%s (exit %d, %s ago)",
+				terminated.Reason,
+				terminated.ExitCode,
+				duration.HumanDuration(time.Since(terminated.FinishedAt.Time)))
+		}
+	}
+
+	return ""
+}
+
+func psCmdRun(args []string) error {
+	ns := getNamespace()
+
+	processes, err := listProcesses(ns)
+	if err != nil {
+		return fmt.Errorf("could not list processes for %s\n%v\n", appName, err)
+	}
+
+	headers := []string{"NAME", "POD", "REVISION", "STATE", "RESTARTS", "AGE", "NODE", "LAST TERMINATION"}
+	data := [][]string{{}}
+	for _, p := range processes {
+		data = append(data, []string{
+			p.name,
+			p.pod.Name,
+			p.pod.Labels[constants.RevisionLabel],
+			podState(p.pod),
+			strconv.Itoa(int(podRestarts(p.pod))),
+			duration.HumanDuration(time.Since(p.pod.CreationTimestamp.Time)),
+			p.pod.Spec.NodeName,
+			podLastTermination(p.pod),
+		})
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List application processes",
+	Long: `List each pod of an application with its process type, revision, state,
+restarts, age, node and last termination reason. Example:
+
+feisty ps -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := psCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	psCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(psCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+// deleteProcesses deletes the pods matching target, the deployment takes care
+// of replacing them
+func deleteProcesses(target string, gracePeriodSeconds *int64) error {
+	ns := getNamespace()
+
+	processes, err := listProcesses(ns)
+	if err != nil {
+		return fmt.Errorf("could not list processes for %s\n%v\n", appName, err)
+	}
+
+	found := findProcesses(processes, target)
+	if len(found) == 0 {
+		return fmt.Errorf("no process %s found for %s\n", target, appName)
+	}
+
+	for _, p := range found {
+		err := kubeClient.CoreV1().Pods(ns).Delete(p.pod.Name, &v1.DeleteOptions{
+			GracePeriodSeconds: gracePeriodSeconds,
+		})
+		if err != nil {
+			return fmt.Errorf("there was an error deleting %s (%s)\n%v\n", p.name, p.pod.Name, err)
+		}
+
+		fmt.Printf("%s (%s) was deleted\n", p.name, p.pod.Name)
+	}
+
+	return nil
+}
+
+var psRestartCmd = &cobra.Command{
+	Use:   "ps:restart",
+	Short: "Restart application processes",
+	Long: `Restart a single process, or every process of a type, by deleting its pod
+and letting it be replaced. Use apps:restart to restart the whole application.
+Example:
+
+feisty ps:restart web.1 -a application-sample
+feisty ps:restart web -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a process name is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := deleteProcesses(args[0], nil); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	psRestartCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(psRestartCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var psStopCmd = &cobra.Command{
+	Use:   "ps:stop",
+	Short: "Stop application processes",
+	Long: `Stop a single process, or every process of a type, immediately without
+waiting for a graceful shutdown. The pod is replaced as long as the application
+has replicas. Example:
+
+feisty ps:stop web.1 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a process name is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		gracePeriodSeconds := int64(0)
+		if err := deleteProcesses(args[0], &gracePeriodSeconds); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	psStopCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(psStopCmd)
+}
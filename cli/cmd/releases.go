@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseRevision parses a revision given on the command line, e.g. v5 or 5
+func parseRevision(arg string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(arg, "v"))
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("could not parse revision %s", arg)
+	}
+
+	return number, nil
+}
+
+func getRevision(ns string, arg string) (*v1alpha1.ApplicationRevision, error) {
+	number, err := parseRevision(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return feistyClient.ApplicationRevisions(ns).Get(revisions.RevisionName(appName, number), v1.GetOptions{})
+}
+
+type numberedRevision struct {
+	number   int
+	revision v1alpha1.ApplicationRevision
+}
+
+// listRevisions returns the revisions of appName, newest first
+func listRevisions(ns string) ([]numberedRevision, error) {
+	revisionList, err := feistyClient.ApplicationRevisions(ns).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := appName + "-v"
+	var numbered []numberedRevision
+	for _, rev := range revisionList.Items {
+		if !strings.HasPrefix(rev.Name, prefix) {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.TrimPrefix(rev.Name, prefix))
+		if err != nil {
+			continue
+		}
+
+		numbered = append(numbered, numberedRevision{number, rev})
+	}
+
+	sort.Slice(numbered, func(i, j int) bool {
+		return numbered[i].number > numbered[j].number
+	})
+
+	return numbered, nil
+}
+
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+
+	return digest
+}
+
+func releasesCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	current, _ := revisions.CurrentRevisionNumber(*app)
+
+	numbered, err := listRevisions(ns)
+	if err != nil {
+		return fmt.Errorf("could not list releases for %s\n%v\n", appName, err)
+	}
+
+	headers := []string{"VERSION", "STATUS", "IMAGE", "DIGEST", "CREATED", "AUTHOR", "CHANGE", ""}
+	data := [][]string{{}}
+	for _, n := range numbered {
+		var markers []string
+		if n.number == current {
+			markers = append(markers, "current")
+		}
+
+		if revisions.IsPinned(n.revision) {
+			markers = append(markers, "pinned")
+		}
+
+		data = append(data, []string{
+			"v" + strconv.Itoa(n.number),
+			string(n.revision.Status.Phase),
+			n.revision.Spec.App.Image,
+			shortDigest(n.revision.Spec.ImageDigest),
+			n.revision.CreationTimestamp.Format("2006-01-02 15:04:05"),
+			n.revision.Spec.Change.Author,
+			n.revision.Spec.Change.Summary,
+			strings.Join(markers, ", "),
+		})
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var releasesCmd = &cobra.Command{
+	Use:   "releases",
+	Short: "List application releases",
+	Long: `List the releases (revisions) of an application, newest first. Example:
+
+feisty releases -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(releasesCmd)
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/hashing"
+	"github.com/r3labs/diff"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+)
+
+const maskedValue = "********"
+
+var releasesDiffShowValues bool
+var releasesDiffFormat string
+
+var releasesDiffChangeTypes = map[string]string{
+	diff.CREATE: "added",
+	diff.DELETE: "removed",
+	diff.UPDATE: "changed",
+}
+
+var releasesDiffChangeMarkers = map[string]string{
+	"added":   "+",
+	"removed": "-",
+	"changed": "~",
+}
+
+type releaseChange struct {
+	Type  string      `json:"type"`
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// releaseAppFields returns the app spec of a release keyed by the field names
+// used in manifests, leaving out unset fields
+func releaseAppFields(rev *v1alpha1.ApplicationRevision) (map[string]interface{}, error) {
+	canonical, err := hashing.Canonical(rev.Spec.App)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(canonical, &fields); err != nil {
+		return nil, err
+	}
+
+	// the config secret name follows the config, which is diffed by key
+	delete(fields, "appConfigRef")
+	if rev.Spec.ImageDigest != "" {
+		fields["imageDigest"] = rev.Spec.ImageDigest
+	}
+
+	return fields, nil
+}
+
+func toReleaseChanges(section string, changelog diff.Changelog, mask bool) []releaseChange {
+	var changes []releaseChange
+	for _, change := range changelog {
+		c := releaseChange{
+			Type:  releasesDiffChangeTypes[change.Type],
+			Field: section + "." + strings.Join(change.Path, "."),
+			From:  change.From,
+			To:    change.To,
+		}
+
+		if mask {
+			if c.From != nil {
+				c.From = maskedValue
+			}
+
+			if c.To != nil {
+				c.To = maskedValue
+			}
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes
+}
+
+// diffReleases returns the app spec fields and config keys that were added,
+// removed or changed going from one release to another
+func diffReleases(from *v1alpha1.ApplicationRevision, to *v1alpha1.ApplicationRevision, showValues bool) ([]releaseChange, error) {
+	fromApp, err := releaseAppFields(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toApp, err := releaseAppFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	appChangelog, err := diff.Diff(fromApp, toApp)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgChangelog, err := diff.Diff(from.Spec.Cfg.KeyValuePairs, to.Spec.Cfg.KeyValuePairs)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := toReleaseChanges("app", appChangelog, false)
+	changes = append(changes, toReleaseChanges("config", cfgChangelog, !showValues)...)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
+
+func formatChangeValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(encoded)
+}
+
+func releasesDiffCmdRun(args []string) error {
+	ns := getNamespace()
+
+	if releasesDiffFormat != "text" && releasesDiffFormat != "json" {
+		return fmt.Errorf("unknown format %s, expected text or json\n", releasesDiffFormat)
+	}
+
+	from, err := getRevision(ns, args[0])
+	if err != nil {
+		return fmt.Errorf("could not load release %s of %s\n%v\n", args[0], appName, err)
+	}
+
+	to, err := getRevision(ns, args[1])
+	if err != nil {
+		return fmt.Errorf("could not load release %s of %s\n%v\n", args[1], appName, err)
+	}
+
+	changes, err := diffReleases(from, to, releasesDiffShowValues)
+	if err != nil {
+		return fmt.Errorf("could not diff %s and %s\n%v\n", from.Name, to.Name, err)
+	}
+
+	if releasesDiffFormat == "json" {
+		if changes == nil {
+			changes = []releaseChange{}
+		}
+
+		encoded, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(encoded))
+		return nil
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("%s and %s are identical\n", from.Name, to.Name)
+		return nil
+	}
+
+	headers := []string{"", "FIELD", "FROM", "TO"}
+	var data [][]string
+	for _, change := range changes {
+		data = append(data, []string{
+			releasesDiffChangeMarkers[change.Type],
+			change.Field,
+			formatChangeValue(change.From),
+			formatChangeValue(change.To),
+		})
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var releasesDiffCmd = &cobra.Command{
+	Use:   "releases:diff",
+	Short: "Show what changed between two releases",
+	Long: `Show the app settings and config keys that were added, removed or changed
+between two releases. Config values are masked unless --show-values is given.
+Example:
+
+feisty releases:diff v3 v7 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("two releases are required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesDiffCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesDiffCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	releasesDiffCmd.Flags().BoolVar(&releasesDiffShowValues, "show-values", false, "show config values instead of masking them")
+	releasesDiffCmd.Flags().StringVar(&releasesDiffFormat, "format", "text", "output format: text, json")
+	rootCmd.AddCommand(releasesDiffCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+func releasesInfoCmdRun(args []string) error {
+	ns := getNamespace()
+
+	rev, err := getRevision(ns, args[0])
+	if err != nil {
+		return fmt.Errorf("could not load release %s of %s\n%v\n", args[0], appName, err)
+	}
+
+	digest := rev.Spec.ImageDigest
+	if digest == "" {
+		digest = "(not resolved yet)"
+	}
+
+	headers := []string{"KEY", "VALUE"}
+	data := [][]string{
+		{},
+		{"Release", rev.Name},
+		{"Created", rev.CreationTimestamp.Format("2006-01-02 15:04:05")},
+		{"Status", string(rev.Status.Phase)},
+		{"Status message", rev.Status.Message},
+		{"Author", rev.Spec.Change.Author},
+		{"Cause", rev.Spec.Change.Cause},
+		{"Change", rev.Spec.Change.Summary},
+		{"Message", rev.Spec.Change.Message},
+		{"Promoted from", rev.Spec.Change.PromotedFrom},
+		{"Image", rev.Spec.App.Image},
+		{"Digest", digest},
+		{"Pinned image", revisions.PinnedImage(rev.Spec)},
+		{"Replicas", strconv.Itoa(rev.Spec.App.Replicas)},
+		{"Port", strconv.Itoa(rev.Spec.App.Port)},
+		{"Config keys", strconv.Itoa(len(rev.Spec.Cfg.KeyValuePairs))},
+		{"App hash", rev.Spec.AppHash},
+		{"Config hash", rev.Spec.CfgHash},
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var releasesInfoCmd = &cobra.Command{
+	Use:   "releases:info",
+	Short: "Show release details",
+	Long: `Show the details of a release, including the image tag it was deployed
+with and the digest that tag resolved to. Example:
+
+feisty releases:info v5 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a release is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesInfoCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesInfoCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(releasesInfoCmd)
+}
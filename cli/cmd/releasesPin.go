@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func setReleasePinned(args []string, pinned bool) error {
+	ns := getNamespace()
+
+	rev, err := getRevision(ns, args[0])
+	if err != nil {
+		return fmt.Errorf("could not load release %s of %s\n%v\n", args[0], appName, err)
+	}
+
+	if pinned {
+		if rev.Annotations == nil {
+			rev.Annotations = map[string]string{}
+		}
+
+		rev.Annotations[revisions.PinnedAnnotation] = "true"
+	} else {
+		delete(rev.Annotations, revisions.PinnedAnnotation)
+	}
+
+	if _, err := feistyClient.ApplicationRevisions(ns).Update(rev); err != nil {
+		return fmt.Errorf("there was an error updating %s\n%v", rev.Name, err)
+	}
+
+	if pinned {
+		fmt.Printf("%s in %s was pinned", rev.Name, rev.Namespace)
+	} else {
+		fmt.Printf("%s in %s was unpinned", rev.Name, rev.Namespace)
+	}
+
+	return nil
+}
+
+var releasesPinCmd = &cobra.Command{
+	Use:   "releases:pin",
+	Short: "Protect a release from pruning",
+	Long: `Pin a release so it's never pruned by the revision retention policy. Example:
+
+feisty releases:pin v5 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a release is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setReleasePinned(args, true); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var releasesUnpinCmd = &cobra.Command{
+	Use:   "releases:unpin",
+	Short: "Allow a pinned release to be pruned",
+	Long: `Unpin a release so the revision retention policy applies to it again. Example:
+
+feisty releases:unpin v5 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a release is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setReleasePinned(args, false); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesPinCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	releasesUnpinCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(releasesPinCmd)
+	rootCmd.AddCommand(releasesUnpinCmd)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func releasesResumeCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	canary := app.Status.Canary
+	if canary == nil || canary.CanaryDeployment == "" || canary.Phase != v1alpha1.CanaryPaused {
+		return fmt.Errorf("%s has no paused canary to resume\n", appName)
+	}
+
+	if app.Annotations == nil {
+		app.Annotations = map[string]string{}
+	}
+
+	// the annotation names the step so it can't resume a later pause by mistake
+	app.Annotations[constants.CanaryResumeAnnotation] = canary.ResumeKey()
+	if _, err := feistyClient.Applications(ns).Update(app); err != nil {
+		return fmt.Errorf("there was an error resuming the canary of %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("Resumed the canary %s of %s at step %d of %d\n", canary.CanaryDeployment, app.Name, canary.Step+1, canary.Steps)
+
+	return nil
+}
+
+var releasesResumeCmd = &cobra.Command{
+	Use:   "releases:resume",
+	Short: "Resume a paused canary",
+	Long: `Resume the canary of an application that's paused at a step, moving it on
+to the next step. Example:
+
+feisty releases:resume -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesResumeCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesResumeCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(releasesResumeCmd)
+}
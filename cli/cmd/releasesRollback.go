@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func releasesRollbackCmdRun(args []string) error {
+	ns := getNamespace()
+
+	rev, err := getRevision(ns, args[0])
+	if err != nil {
+		return fmt.Errorf("could not load release %s of %s\n%v\n", args[0], appName, err)
+	}
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	appConfig, exists, err := getOrNewAppConfig(ns)
+	if err != nil {
+		return fmt.Errorf("could not load config %s\n%v\n", appName, err)
+	}
+
+	revisions.Restore(*rev, app, appConfig)
+	annotateChange(&app.ObjectMeta, revisions.CauseRollback)
+	annotateChange(&appConfig.ObjectMeta, revisions.CauseRollback)
+	if err := saveAppConfig(ns, appConfig, exists); err != nil {
+		return fmt.Errorf("there was an error restoring the config of %s\n%v", appName, err)
+	}
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error rolling back %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s in %s was rolled back to %s (%s)\n", app.Name, app.Namespace, rev.Name, app.Spec.Image)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var releasesRollbackCmd = &cobra.Command{
+	Use:   "releases:rollback",
+	Short: "Roll back to a previous release",
+	Long: `Restore the application spec and config of a previous release. The image
+is deployed by digest when the release recorded one, so the exact code that
+ran in that release runs again. Example:
+
+feisty releases:rollback v5 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a release is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesRollbackCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesRollbackCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(releasesRollbackCmd)
+	addChangeFlags(releasesRollbackCmd)
+	rootCmd.AddCommand(releasesRollbackCmd)
+}
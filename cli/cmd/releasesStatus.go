@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"os"
+	"strconv"
+	"time"
+)
+
+func formatStatusTime(t *v1.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func releasesStatusCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	strategy := app.Spec.Strategy
+	if strategy == "" {
+		strategy = v1alpha1.RollingStrategy
+	}
+
+	headers := []string{"KEY", "VALUE"}
+	data := [][]string{
+		{},
+		{"Strategy", string(strategy)},
+	}
+
+	if condition := findCondition(*app, v1alpha1.ApplicationVolumesReady); condition != nil && condition.Reason == v1alpha1.StrategyOverriddenReason {
+		data[1][1] = string(v1alpha1.RollingStrategy) + " (" + string(strategy) + " overridden)"
+		data = append(data, []string{"Strategy message", condition.Message})
+	}
+
+	if current, _ := revisions.CurrentRevisionNumber(*app); current > 0 {
+		data = append(data, []string{"Release", "v" + strconv.Itoa(current)})
+
+		rev, err := feistyClient.ApplicationRevisions(ns).Get(revisions.RevisionName(appName, current), v1.GetOptions{})
+		if err == nil {
+			data = append(data,
+				[]string{"Status", string(rev.Status.Phase)},
+				[]string{"Status message", rev.Status.Message},
+			)
+		}
+	}
+
+	if bg := app.Status.BlueGreen; bg != nil {
+		scaleDown := ""
+		if bg.PreviousDeployment != "" && bg.ScaleDownTime != nil {
+			scaleDown = duration.HumanDuration(time.Until(bg.ScaleDownTime.Time))
+		}
+
+		data = append(data,
+			[]string{"Active deployment", bg.ActiveDeployment},
+			[]string{"Preview deployment", bg.PreviewDeployment},
+			[]string{"Previous deployment", bg.PreviousDeployment},
+			[]string{"Previous kept for", scaleDown},
+			[]string{"Last switched", formatStatusTime(bg.SwitchTime)},
+		)
+	}
+
+	if c := app.Status.Canary; c != nil {
+		step := ""
+		if c.CanaryDeployment != "" {
+			step = fmt.Sprintf("%d of %d", c.Step+1, c.Steps)
+			if c.Step >= c.Steps {
+				step = "promoting"
+			}
+		}
+
+		data = append(data,
+			[]string{"Stable deployment", c.StableDeployment},
+			[]string{"Canary deployment", c.CanaryDeployment},
+			[]string{"Aborted deployment", c.AbortedDeployment},
+			[]string{"Canary phase", string(c.Phase)},
+			[]string{"Canary step", step},
+			[]string{"Canary weight", strconv.Itoa(c.Weight) + "%"},
+			[]string{"Step started", formatStatusTime(c.StepStartTime)},
+			[]string{"Canary message", c.Message},
+		)
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var releasesStatusCmd = &cobra.Command{
+	Use:   "releases:status",
+	Short: "Show the rollout status of the current release",
+	Long: `Show how the current release of an application is being rolled out,
+including the deployments of the bluegreen strategy and the step and traffic
+weight of a canary. Example:
+
+feisty releases:status -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesStatusCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesStatusCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(releasesStatusCmd)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/constants"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func releasesSwitchCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	bg := app.Status.BlueGreen
+	if bg == nil || bg.PreviousDeployment == "" {
+		return fmt.Errorf("%s has no previous deployment to switch back to, it's only kept for a while after traffic was switched\n", appName)
+	}
+
+	deployment, err := kubeClient.AppsV1().Deployments(ns).Get(bg.PreviousDeployment, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load deployment %s\n%v\n", bg.PreviousDeployment, err)
+	}
+
+	revision, ok := deployment.Spec.Template.Labels[constants.RevisionLabel]
+	if !ok {
+		return fmt.Errorf("deployment %s isn't labelled with the release it runs\n", deployment.Name)
+	}
+
+	rev, err := getRevision(ns, revision)
+	if err != nil {
+		return fmt.Errorf("could not load release %s of %s\n%v\n", revision, appName, err)
+	}
+
+	appConfig, exists, err := getOrNewAppConfig(ns)
+	if err != nil {
+		return fmt.Errorf("could not load config %s\n%v\n", appName, err)
+	}
+
+	// unlike a rollback the image isn't pinned and the config secret is the one
+	// the release ran with, so the spec matches the previous deployment exactly
+	// and the controller switches traffic to it instead of rolling out new pods
+	current := app.Spec
+	app.Spec = *rev.Spec.App.DeepCopy()
+	revisions.KeepState(current, &app.Spec)
+	appConfig.Spec = *rev.Spec.Cfg.DeepCopy()
+	annotateChange(&app.ObjectMeta, revisions.CauseRollback)
+	annotateChange(&appConfig.ObjectMeta, revisions.CauseRollback)
+
+	// the app goes first, the config controller would otherwise point the
+	// current image at the previous config and roll that out
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error switching %s\n%v", app.Name, err)
+	}
+
+	if err := saveAppConfig(ns, appConfig, exists); err != nil {
+		return fmt.Errorf("there was an error restoring the config of %s\n%v", appName, err)
+	}
+
+	fmt.Printf("%s in %s was switched back to %s (%s)\n", app.Name, app.Namespace, rev.Name, app.Spec.Image)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var releasesSwitchCmd = &cobra.Command{
+	Use:   "releases:switch",
+	Short: "Switch traffic back to the previous deployment",
+	Long: `Switch the traffic of a bluegreen application back to the deployment it was
+last switched away from. The previous deployment is still running for a while
+after a switch, so no new pods have to be rolled out. Example:
+
+feisty releases:switch -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := releasesSwitchCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	releasesSwitchCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(releasesSwitchCmd)
+	addChangeFlags(releasesSwitchCmd)
+	rootCmd.AddCommand(releasesSwitchCmd)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/reviewapps"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+var reviewAppsTemplate string
+var reviewAppsCreateName string
+var reviewAppsCreateImage string
+var reviewAppsCreateTTL time.Duration
+var reviewAppsCreateConfigs []string
+
+// getReviewAppTemplate returns the template named by --template, or the only
+// template for appName when it isn't given
+func getReviewAppTemplate(ns string) (*v1alpha1.ReviewAppTemplate, error) {
+	if reviewAppsTemplate != "" {
+		return feistyClient.ReviewAppTemplates(ns).Get(reviewAppsTemplate, v1.GetOptions{})
+	}
+
+	templates, err := feistyClient.ReviewAppTemplates(ns).List(v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []v1alpha1.ReviewAppTemplate
+	var names []string
+	for _, tmpl := range templates.Items {
+		if tmpl.Spec.App == appName {
+			matches = append(matches, tmpl)
+			names = append(names, tmpl.Name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%s has no review app template", appName)
+	case 1:
+		return &matches[0], nil
+	}
+
+	return nil, fmt.Errorf("%s has several review app templates (%s), pick one with --template", appName, strings.Join(names, ", "))
+}
+
+func reviewAppsCreateCmdRun(args []string) error {
+	ns := getNamespace()
+
+	parsedConfigs, err := parseArgs(reviewAppsCreateConfigs)
+	if err != nil {
+		return fmt.Errorf("could not parse configs\n%v\n", err)
+	}
+
+	tmpl, err := getReviewAppTemplate(ns)
+	if err != nil {
+		return fmt.Errorf("could not load the review app template of %s\n%v\n", appName, err)
+	}
+
+	parent, err := feistyClient.Applications(ns).Get(tmpl.Spec.App, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", tmpl.Spec.App, err)
+	}
+
+	parentCfg, err := feistyClient.ApplicationConfigs(ns).Get(tmpl.Spec.App, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		parentCfg = nil
+	} else if err != nil {
+		return fmt.Errorf("could not load config %s\n%v\n", tmpl.Spec.App, err)
+	}
+
+	suffix := reviewAppsCreateName
+	if suffix == "" {
+		suffix = utilrand.String(5)
+	}
+
+	app := reviewapps.NewApplication(*tmpl, *parent, reviewapps.Name(parent.Name, suffix))
+	if reviewAppsCreateImage != "" {
+		app.Spec.Image = reviewAppsCreateImage
+	}
+
+	if reviewAppsCreateTTL > 0 {
+		app.Annotations = map[string]string{reviewapps.TTLAnnotation: reviewAppsCreateTTL.String()}
+	}
+
+	annotateChange(&app.ObjectMeta, "")
+	created, err := feistyClient.Applications(ns).Create(&app)
+	if err != nil {
+		return fmt.Errorf("There was an error creating the review app: \n%v\n", err)
+	}
+
+	appConfig := reviewapps.NewConfig(*tmpl, parentCfg, *created)
+	for k, v := range parsedConfigs {
+		appConfig.Spec.KeyValuePairs[k] = v
+	}
+
+	annotateChange(&appConfig.ObjectMeta, "")
+	if _, err := feistyClient.ApplicationConfigs(ns).Create(&appConfig); err != nil {
+		return fmt.Errorf("The review app %s in namespace %s was created but its config was not: \n%v\n", created.Name, ns, err)
+	}
+
+	fmt.Printf("The review app %s in namespace %s was created from %s!\n", created.Name, ns, parent.Name)
+	for _, domain := range created.Spec.Domains {
+		fmt.Printf("It will be available at %s\n", domain.Host)
+	}
+
+	if expiresAt, ok := reviewapps.ExpiresAt(*created, *tmpl); ok {
+		fmt.Printf("It expires at %s\n", expiresAt.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	if waitForRollout {
+		return waitForApp(ns, created)
+	}
+
+	return nil
+}
+
+var reviewAppsCreateCmd = &cobra.Command{
+	Use:   "review-apps:create",
+	Short: "Create a review app",
+	Long: `Create a review app from the review app template of an application. The
+review app is a clone of the application with its own name, domain and config,
+it's deleted once the template's TTL expires. Example:
+
+feisty review-apps:create -a application-sample --name pr-42 --image registry/app:pr-42
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := reviewAppsCreateCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	reviewAppsCreateCmd.Flags().StringVarP(&appName, "app name", "a", "", "the application to clone")
+	reviewAppsCreateCmd.Flags().StringVar(&reviewAppsTemplate, "template", "", "the review app template to use, when the app has several")
+	reviewAppsCreateCmd.Flags().StringVar(&reviewAppsCreateName, "name", "", "suffix for the review app name, e.g. pr-42 (defaults to a random one)")
+	reviewAppsCreateCmd.Flags().StringVar(&reviewAppsCreateImage, "image", "", "the image to deploy instead of the template's")
+	reviewAppsCreateCmd.Flags().DurationVar(&reviewAppsCreateTTL, "ttl", 0, "how long the review app lives instead of the template's TTL, e.g. 24h")
+	reviewAppsCreateCmd.Flags().StringArrayVarP(&reviewAppsCreateConfigs, "config", "c", []string{}, "config to set as KEY=value, may be repeated")
+	addWaitFlags(reviewAppsCreateCmd)
+	rootCmd.AddCommand(reviewAppsCreateCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/constants"
+	"github.com/mrferos/feisty/reviewapps"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"os"
+)
+
+func reviewAppsDestroyCmdRun(args []string) error {
+	ns := getNamespace()
+	name := args[0]
+
+	app, err := feistyClient.Applications(ns).Get(name, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load review app %s\n%v\n", name, err)
+	}
+
+	if app.Labels[reviewapps.ParentLabel] != appName {
+		return fmt.Errorf("%s isn't a review app of %s\n", name, appName)
+	}
+
+	// revisions aren't owned by the app, so they'd outlive it
+	revisionList, err := feistyClient.ApplicationRevisions(ns).List(v1.ListOptions{
+		LabelSelector: labels.Set{constants.AppLabel: name}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list the releases of %s\n%v\n", name, err)
+	}
+
+	for _, rev := range revisionList.Items {
+		if err := feistyClient.ApplicationRevisions(ns).Delete(rev.Name, &v1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete release %s\n%v\n", rev.Name, err)
+		}
+	}
+
+	if err := feistyClient.ApplicationConfigs(ns).Delete(name, &v1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete the config of %s\n%v\n", name, err)
+	}
+
+	if err := feistyClient.Applications(ns).Delete(name, &v1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete review app %s\n%v\n", name, err)
+	}
+
+	fmt.Printf("The review app %s in namespace %s was destroyed\n", name, ns)
+
+	return nil
+}
+
+var reviewAppsDestroyCmd = &cobra.Command{
+	Use:   "review-apps:destroy",
+	Short: "Destroy a review app",
+	Long: `Delete a review app along with its config and releases before its TTL
+expires. Example:
+
+feisty review-apps:destroy application-sample-pr-42 -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a review app name is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := reviewAppsDestroyCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	reviewAppsDestroyCmd.Flags().StringVarP(&appName, "app name", "a", "", "the application the review app was cloned from")
+	rootCmd.AddCommand(reviewAppsDestroyCmd)
+}
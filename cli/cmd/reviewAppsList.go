@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/mrferos/feisty/reviewapps"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"os"
+	"strings"
+	"time"
+)
+
+func reviewAppsListCmdRun(args []string) error {
+	ns := getNamespace()
+
+	apps, err := feistyClient.Applications(ns).List(v1.ListOptions{
+		LabelSelector: labels.Set{reviewapps.ParentLabel: appName}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list review apps of %s\n%v\n", appName, err)
+	}
+
+	templateList, err := feistyClient.ReviewAppTemplates(ns).List(v1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("could not list review app templates\n%v\n", err)
+	}
+
+	templates := map[string]v1alpha1.ReviewAppTemplate{}
+	for _, tmpl := range templateList.Items {
+		templates[tmpl.Name] = tmpl
+	}
+
+	headers := []string{"NAME", "TEMPLATE", "IMAGE", "HOSTS", "AGE", "EXPIRES IN"}
+	data := [][]string{{}}
+	for _, app := range apps.Items {
+		var hosts []string
+		for _, domain := range app.Spec.Domains {
+			hosts = append(hosts, domain.Host)
+		}
+
+		templateName := app.Labels[reviewapps.TemplateLabel]
+		expires := "never"
+		if expiresAt, ok := reviewapps.ExpiresAt(app, templates[templateName]); ok {
+			expires = duration.HumanDuration(time.Until(expiresAt))
+		}
+
+		data = append(data, []string{
+			app.Name,
+			templateName,
+			app.Spec.Image,
+			strings.Join(hosts, ","),
+			duration.HumanDuration(time.Since(app.CreationTimestamp.Time)),
+			expires,
+		})
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var reviewAppsListCmd = &cobra.Command{
+	Use:   "review-apps:list",
+	Short: "List the review apps of an application",
+	Long: `List the review apps cloned from an application. Example:
+
+feisty review-apps:list -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := reviewAppsListCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	reviewAppsListCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(reviewAppsListCmd)
+}
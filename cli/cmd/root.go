@@ -6,6 +6,7 @@ import (
 	"github.com/mrferos/feisty/cli/client"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
 	"os"
 	"strings"
 )
@@ -14,6 +15,7 @@ var cfgFile string
 var appNamespace string
 var appName string
 var feistyClient *client.FeistyV1Alpha1Client
+var kubeClient kubernetes.Interface
 
 var rootCmd = &cobra.Command{
 	Short: "A Feisty CLI",
@@ -59,16 +61,25 @@ func getNamespace() string {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, initClients)
 	rootCmd.PersistentFlags().StringVarP(&appNamespace, "namespace", "n", "", "target namespace (defaults to app name)")
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.feisty.yml")
+}
 
+// initClients connects to the cluster when a command runs rather than when
+// the package loads, so the package's tests don't need a kubeconfig
+func initClients() {
 	var err error
 	feistyClient, err = client.GetFeistyClient()
 	if err != nil {
 		// TODO: make this cleaner
 		panic(err)
 	}
+
+	kubeClient, err = client.GetKubernetesClient()
+	if err != nil {
+		panic(err)
+	}
 }
 
 func initConfig() {
@@ -87,6 +98,6 @@ func initConfig() {
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("Could not load config file: ", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Could not load config file: ", viper.ConfigFileUsed())
 	}
 }
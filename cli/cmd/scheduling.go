@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// schedulingOf returns the scheduling of an app, adding it when it has none
+func schedulingOf(app *v1alpha1.Application) *v1alpha1.Scheduling {
+	if app.Spec.Scheduling == nil {
+		app.Spec.Scheduling = &v1alpha1.Scheduling{}
+	}
+
+	return app.Spec.Scheduling
+}
+
+// parseNodeSelector parses labels like pool=production,disk=ssd
+func parseNodeSelector(val string) (map[string]string, error) {
+	if val == "" {
+		return nil, nil
+	}
+
+	selector := map[string]string{}
+	for _, pair := range strings.Split(val, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("could not parse node selector: %s", pair)
+		}
+
+		selector[parts[0]] = parts[1]
+	}
+
+	return selector, nil
+}
+
+// parseTolerations parses taints in the kubectl taint format, like
+// pool=production:NoSchedule,gpu:NoExecute
+func parseTolerations(val string) ([]corev1.Toleration, error) {
+	if val == "" {
+		return nil, nil
+	}
+
+	var tolerations []corev1.Toleration
+	for _, taint := range strings.Split(val, ",") {
+		toleration := corev1.Toleration{Operator: corev1.TolerationOpExists}
+		keyValue := taint
+		if i := strings.LastIndex(taint, ":"); i != -1 {
+			keyValue = taint[:i]
+			toleration.Effect = corev1.TaintEffect(taint[i+1:])
+			switch toleration.Effect {
+			case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+			default:
+				return nil, fmt.Errorf("could not parse toleration effect: %s", taint)
+			}
+		}
+
+		parts := strings.SplitN(keyValue, "=", 2)
+		toleration.Key = parts[0]
+		if len(parts) == 2 {
+			toleration.Operator = corev1.TolerationOpEqual
+			toleration.Value = parts[1]
+		}
+
+		if toleration.Key == "" {
+			return nil, fmt.Errorf("could not parse toleration: %s", taint)
+		}
+
+		tolerations = append(tolerations, toleration)
+	}
+
+	return tolerations, nil
+}
+
+// parseTopologySpread parses node label keys to spread the pods across, like
+// topology.kubernetes.io/zone
+func parseTopologySpread(val string) []v1alpha1.TopologySpread {
+	if val == "" {
+		return nil
+	}
+
+	var spread []v1alpha1.TopologySpread
+	for _, key := range strings.Split(val, ",") {
+		spread = append(spread, v1alpha1.TopologySpread{TopologyKey: key})
+	}
+
+	return spread
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseNodeSelector(t *testing.T) {
+	tests := []struct {
+		val     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{val: "", want: nil},
+		{val: "pool=production", want: map[string]string{"pool": "production"}},
+		{val: "pool=production,disk=ssd", want: map[string]string{"pool": "production", "disk": "ssd"}},
+		{val: "pool=", want: map[string]string{"pool": ""}},
+		{val: "a=b=c", want: map[string]string{"a": "b=c"}},
+		{val: "pool", wantErr: true},
+		{val: "=production", wantErr: true},
+		{val: "pool=production,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseNodeSelector(tt.val)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseNodeSelector(%q) error = %v, wantErr %t", tt.val, err, tt.wantErr)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseNodeSelector(%q) = %v, want %v", tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestParseTolerations(t *testing.T) {
+	tests := []struct {
+		val     string
+		want    []corev1.Toleration
+		wantErr bool
+	}{
+		{val: "", want: nil},
+		{
+			val:  "pool=production:NoSchedule",
+			want: []corev1.Toleration{{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "production", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{
+			val:  "gpu:NoExecute",
+			want: []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute}},
+		},
+		{
+			val:  "dedicated",
+			want: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		},
+		{
+			val: "pool=production:NoSchedule,gpu:PreferNoSchedule",
+			want: []corev1.Toleration{
+				{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "production", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectPreferNoSchedule},
+			},
+		},
+		{
+			val:  "example.com/pool=a:NoSchedule",
+			want: []corev1.Toleration{{Key: "example.com/pool", Operator: corev1.TolerationOpEqual, Value: "a", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{val: "gpu:Sometimes", wantErr: true},
+		{val: ":NoSchedule", wantErr: true},
+		{val: "=production", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTolerations(tt.val)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTolerations(%q) error = %v, wantErr %t", tt.val, err, tt.wantErr)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTolerations(%q) = %+v, want %+v", tt.val, got, tt.want)
+		}
+	}
+}
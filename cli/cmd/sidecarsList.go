@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func sidecarsListCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	headers := []string{"NAME", "KIND", "IMAGE", "COMMAND", "PORTS", "CONFIG"}
+	data := [][]string{{}}
+	for _, kind := range []string{"sidecar", "init"} {
+		for _, container := range *extraContainersOf(app, kind == "init") {
+			var ports []string
+			for _, port := range container.Ports {
+				ports = append(ports, strconv.Itoa(int(port.ContainerPort)))
+			}
+
+			data = append(data, []string{
+				container.Name,
+				kind,
+				container.Image,
+				strings.Join(append(append([]string{}, container.Command...), container.Args...), " "),
+				strings.Join(ports, ", "),
+				inheritsConfig(container),
+			})
+		}
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+func inheritsConfig(container v1alpha1.ExtraContainer) string {
+	if container.InheritConfig {
+		return "inherited"
+	}
+
+	return ""
+}
+
+var sidecarsListCmd = &cobra.Command{
+	Use:   "sidecars:list",
+	Short: "List sidecars and init containers",
+	Long: `List the extra containers running in the pods of an application. Example:
+
+feisty sidecars:list -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := sidecarsListCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sidecarsListCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(sidecarsListCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func sidecarsRemoveCmdRun(args []string) error {
+	ns := getNamespace()
+	name := args[0]
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	containers := extraContainersOf(app, sidecarsInit)
+	var kept []v1alpha1.ExtraContainer
+	for _, container := range *containers {
+		if container.Name != name {
+			kept = append(kept, container)
+		}
+	}
+
+	if len(kept) == len(*containers) {
+		return fmt.Errorf("%s has no container named %s\n", app.Name, name)
+	}
+
+	*containers = kept
+	annotateChange(&app.ObjectMeta, "")
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error updating %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s was removed from %s in %s\n", name, app.Name, app.Namespace)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var sidecarsRemoveCmd = &cobra.Command{
+	Use:   "sidecars:remove NAME",
+	Short: "Remove a sidecar or init container",
+	Long: `Stop running an extra container in the pods of an application. Example:
+
+feisty sidecars:remove cloudsql -a application-sample
+feisty sidecars:remove migrate --init -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("a container name is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := sidecarsRemoveCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sidecarsRemoveCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	sidecarsRemoveCmd.Flags().BoolVar(&sidecarsInit, "init", false, "remove an init container")
+	addWaitFlags(sidecarsRemoveCmd)
+	addChangeFlags(sidecarsRemoveCmd)
+	rootCmd.AddCommand(sidecarsRemoveCmd)
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"os"
+	"sort"
+	"strings"
+)
+
+var sidecarsImage string
+var sidecarsEnv []string
+var sidecarsPorts []int
+var sidecarsInheritConfig bool
+var sidecarsInit bool
+var sidecarsMounts []string
+
+// extraContainersOf returns the sidecars, or the init containers, of an app
+func extraContainersOf(app *v1alpha1.Application, init bool) *[]v1alpha1.ExtraContainer {
+	if init {
+		return &app.Spec.InitContainers
+	}
+
+	return &app.Spec.Sidecars
+}
+
+func sidecarsSetCmdRun(args []string) error {
+	ns := getNamespace()
+	name := args[0]
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	if name == app.Name {
+		return fmt.Errorf("a container can't be named %s, that's the application's container\n", name)
+	}
+
+	for _, other := range *extraContainersOf(app, !sidecarsInit) {
+		if other.Name == name {
+			kind := "a sidecar"
+			if !sidecarsInit {
+				kind = "an init container"
+			}
+
+			return fmt.Errorf("%s is already the name of %s, remove it first\n", name, kind)
+		}
+	}
+
+	env, err := parseArgs(sidecarsEnv)
+	if err != nil {
+		return fmt.Errorf("could not parse env\n%v\n", err)
+	}
+
+	extra := v1alpha1.ExtraContainer{
+		Name:          name,
+		Image:         sidecarsImage,
+		InheritConfig: sidecarsInheritConfig,
+	}
+
+	if len(args) > 1 {
+		extra.Command = args[1:]
+	}
+
+	var keys []string
+	for key := range env {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		extra.Env = append(extra.Env, corev1.EnvVar{Name: key, Value: env[key]})
+	}
+
+	for _, port := range sidecarsPorts {
+		extra.Ports = append(extra.Ports, corev1.ContainerPort{ContainerPort: int32(port)})
+	}
+
+	for _, mount := range sidecarsMounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "ro") {
+			return fmt.Errorf("could not parse mount %s, expected VOLUME:PATH or VOLUME:PATH:ro\n", mount)
+		}
+
+		extra.VolumeMounts = append(extra.VolumeMounts, corev1.VolumeMount{
+			Name:      parts[0],
+			MountPath: parts[1],
+			ReadOnly:  len(parts) == 3,
+		})
+	}
+
+	containers := extraContainersOf(app, sidecarsInit)
+	replaced := false
+	for i, container := range *containers {
+		if container.Name == name {
+			(*containers)[i] = extra
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		*containers = append(*containers, extra)
+	}
+
+	annotateChange(&app.ObjectMeta, "")
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error updating %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("%s was set on %s in %s\n", name, app.Name, app.Namespace)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var sidecarsSetCmd = &cobra.Command{
+	Use:   "sidecars:set NAME [COMMAND...]",
+	Short: "Add or replace a sidecar or init container",
+	Long: `Run an extra container in every pod of an application, next to the
+application's container or, with --init, before it starts. A container of the
+same name is replaced. Example:
+
+feisty sidecars:set cloudsql --image gcr.io/cloudsql-docker/gce-proxy:1.17 -a application-sample -- /cloud_sql_proxy -instances=project:region:db=tcp:5432
+feisty sidecars:set migrate --init --inherit-config --image registry/app:v2 -a application-sample -- ./migrate
+feisty sidecars:set shipper --image fluent/fluent-bit -e LOG_LEVEL=info --mount logs:/var/log/app:ro -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("a container name is required")
+		}
+
+		if errs := validation.IsDNS1123Label(args[0]); len(errs) > 0 {
+			return fmt.Errorf("%s isn't a valid container name: %s", args[0], strings.Join(errs, ", "))
+		}
+
+		if sidecarsImage == "" {
+			return errors.New("an image is required")
+		}
+
+		if strings.ContainsAny(sidecarsImage, " \t\n") {
+			return fmt.Errorf("%q isn't a valid image", sidecarsImage)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := sidecarsSetCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sidecarsSetCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	sidecarsSetCmd.Flags().StringVar(&sidecarsImage, "image", "", "the image the container runs")
+	sidecarsSetCmd.Flags().StringArrayVarP(&sidecarsEnv, "env", "e", []string{}, "environment variable as KEY=value, may be repeated")
+	sidecarsSetCmd.Flags().IntSliceVar(&sidecarsPorts, "port", []int{}, "port the container listens on, may be repeated")
+	sidecarsSetCmd.Flags().BoolVar(&sidecarsInheritConfig, "inherit-config", false, "give the container the application's config as environment variables")
+	sidecarsSetCmd.Flags().StringArrayVar(&sidecarsMounts, "mount", []string{}, "mount one of the application's volumes as VOLUME:PATH, or VOLUME:PATH:ro, may be repeated")
+	sidecarsSetCmd.Flags().BoolVar(&sidecarsInit, "init", false, "run the container to completion before the others start")
+	addWaitFlags(sidecarsSetCmd)
+	addChangeFlags(sidecarsSetCmd)
+	rootCmd.AddCommand(sidecarsSetCmd)
+}
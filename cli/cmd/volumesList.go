@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/output"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+// describeVolume returns the kind of a volume and where it comes from
+func describeVolume(volume v1alpha1.ApplicationVolume) (string, string) {
+	switch {
+	case volume.Persistent != nil:
+		source := volume.Persistent.Size.String()
+		if volume.Persistent.StorageClassName != nil {
+			source += " " + *volume.Persistent.StorageClassName
+		}
+
+		if volume.Persistent.AccessMode != "" {
+			source += " " + string(volume.Persistent.AccessMode)
+		}
+
+		if volume.Persistent.Keep {
+			source += ", kept"
+		}
+
+		return "persistent", source
+	case volume.EmptyDir != nil:
+		source := string(volume.EmptyDir.Medium)
+		if volume.EmptyDir.SizeLimit != nil {
+			source += " " + volume.EmptyDir.SizeLimit.String()
+		}
+
+		return "emptyDir", source
+	case volume.ConfigMap != "":
+		return "configMap", volume.ConfigMap
+	}
+
+	return "secret", volume.Secret
+}
+
+func volumesListCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	headers := []string{"NAME", "KIND", "SOURCE", "PATH", "MODE"}
+	data := [][]string{{}}
+	for _, volume := range app.Spec.Volumes {
+		kind, source := describeVolume(volume)
+		mode := "rw"
+		if volume.ReadOnly {
+			mode = "ro"
+		}
+
+		data = append(data, []string{volume.Name, kind, source, volume.MountPath, mode})
+	}
+
+	output.OutputTable(headers, data)
+
+	return nil
+}
+
+var volumesListCmd = &cobra.Command{
+	Use:   "volumes:list",
+	Short: "List volumes",
+	Long: `List the volumes mounted in the pods of an application. Example:
+
+feisty volumes:list -a application-sample
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := volumesListCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	volumesListCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	rootCmd.AddCommand(volumesListCmd)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+func volumesRemoveCmdRun(args []string) error {
+	ns := getNamespace()
+	name := args[0]
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	var kept []v1alpha1.ApplicationVolume
+	for _, volume := range app.Spec.Volumes {
+		if volume.Name != name {
+			kept = append(kept, volume)
+		}
+	}
+
+	if len(kept) == len(app.Spec.Volumes) {
+		return fmt.Errorf("%s has no volume named %s\n", app.Name, name)
+	}
+
+	for _, extra := range append(append([]v1alpha1.ExtraContainer{}, app.Spec.Sidecars...), app.Spec.InitContainers...) {
+		for _, mount := range extra.VolumeMounts {
+			if mount.Name == name {
+				return fmt.Errorf("container %s mounts volume %s, remove it from the container first\n", extra.Name, name)
+			}
+		}
+	}
+
+	app.Spec.Volumes = kept
+	annotateChange(&app.ObjectMeta, "")
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error updating %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("volume %s was removed from %s in %s\n", name, app.Name, app.Namespace)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var volumesRemoveCmd = &cobra.Command{
+	Use:   "volumes:remove NAME",
+	Short: "Remove a volume",
+	Long: `Stop mounting a volume in the application's pods. The data of a persistent
+volume is deleted unless it was set with --keep. Example:
+
+feisty volumes:remove cache -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("a volume name is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := volumesRemoveCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	volumesRemoveCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	addWaitFlags(volumesRemoveCmd)
+	addChangeFlags(volumesRemoveCmd)
+	rootCmd.AddCommand(volumesRemoveCmd)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+var volumesPath string
+var volumesReadOnly bool
+var volumesSize string
+var volumesStorageClass string
+var volumesAccessMode string
+var volumesKeep bool
+var volumesEmptyDir bool
+var volumesMemory bool
+var volumesConfigMap string
+var volumesSecret string
+
+// volumeFromFlags builds a volume from the flags of volumes:set
+func volumeFromFlags(name string) (v1alpha1.ApplicationVolume, error) {
+	volume := v1alpha1.ApplicationVolume{
+		Name:      name,
+		MountPath: volumesPath,
+		ReadOnly:  volumesReadOnly,
+		ConfigMap: volumesConfigMap,
+		Secret:    volumesSecret,
+	}
+
+	var size *resource.Quantity
+	if volumesSize != "" {
+		parsed, err := resource.ParseQuantity(volumesSize)
+		if err != nil {
+			return volume, fmt.Errorf("could not parse size: %s", volumesSize)
+		}
+
+		size = &parsed
+	}
+
+	switch {
+	case volumesEmptyDir:
+		volume.EmptyDir = &v1alpha1.EmptyDirOptions{SizeLimit: size}
+		if volumesMemory {
+			volume.EmptyDir.Medium = corev1.StorageMediumMemory
+		}
+	case size != nil && volumesConfigMap == "" && volumesSecret == "":
+		volume.Persistent = &v1alpha1.PersistentVolumeOptions{
+			Size:       *size,
+			AccessMode: corev1.PersistentVolumeAccessMode(volumesAccessMode),
+			Keep:       volumesKeep,
+		}
+
+		if volumesStorageClass != "" {
+			volume.Persistent.StorageClassName = &volumesStorageClass
+		}
+	}
+
+	sources := 0
+	for _, set := range []bool{volume.Persistent != nil, volume.EmptyDir != nil, volume.ConfigMap != "", volume.Secret != ""} {
+		if set {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		return volume, errors.New("a volume needs exactly one of --size, --empty-dir, --config-map or --secret")
+	}
+
+	return volume, nil
+}
+
+func volumesSetCmdRun(args []string) error {
+	ns := getNamespace()
+
+	app, err := feistyClient.Applications(ns).Get(appName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not load application %s\n%v\n", appName, err)
+	}
+
+	volume, err := volumeFromFlags(args[0])
+	if err != nil {
+		return fmt.Errorf("%v\n", err)
+	}
+
+	replaced := false
+	for i, existing := range app.Spec.Volumes {
+		if existing.Name == volume.Name {
+			app.Spec.Volumes[i] = volume
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		app.Spec.Volumes = append(app.Spec.Volumes, volume)
+	}
+
+	annotateChange(&app.ObjectMeta, "")
+
+	updated, err := feistyClient.Applications(ns).Update(app)
+	if err != nil {
+		return fmt.Errorf("there was an error updating %s\n%v", app.Name, err)
+	}
+
+	fmt.Printf("volume %s was set on %s in %s\n", volume.Name, app.Name, app.Namespace)
+
+	if waitForRollout {
+		return waitForApp(ns, updated)
+	}
+
+	return nil
+}
+
+var volumesSetCmd = &cobra.Command{
+	Use:   "volumes:set NAME",
+	Short: "Add or replace a volume",
+	Long: `Mount a volume in the application's container. A persistent volume gets a
+PersistentVolumeClaim named <app>-<name>, it's deleted with the application
+unless --keep is given. Only one node can attach a ReadWriteOnce volume, so
+it needs a single replica whose pod is replaced rather than rolled, and the
+bluegreen and canary strategies fall back to rolling. Example:
+
+feisty volumes:set uploads --path /app/uploads --size 10Gi --keep -a application-sample
+feisty volumes:set cache --path /tmp/cache --empty-dir --size 1Gi -a application-sample
+feisty volumes:set settings --path /etc/app --config-map app-settings --read-only -a application-sample
+
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("a volume name is required")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := volumesSetCmdRun(args); err != nil {
+			fmt.Print(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	volumesSetCmd.Flags().StringVarP(&appName, "app name", "a", "", "target application")
+	volumesSetCmd.Flags().StringVar(&volumesPath, "path", "", "where the volume is mounted in the application's container")
+	volumesSetCmd.Flags().BoolVar(&volumesReadOnly, "read-only", false, "mount the volume read only")
+	volumesSetCmd.Flags().StringVar(&volumesSize, "size", "", "the size of a persistent volume, or the size limit of an empty dir, e.g. 10Gi")
+	volumesSetCmd.Flags().StringVar(&volumesStorageClass, "storage-class", "", "the storage class of a persistent volume")
+	volumesSetCmd.Flags().StringVar(&volumesAccessMode, "access-mode", "", "ReadWriteOnce (the default), ReadOnlyMany or ReadWriteMany")
+	volumesSetCmd.Flags().BoolVar(&volumesKeep, "keep", false, "keep the persistent volume's data when the application is deleted")
+	volumesSetCmd.Flags().BoolVar(&volumesEmptyDir, "empty-dir", false, "scratch storage that lives as long as the pod")
+	volumesSetCmd.Flags().BoolVar(&volumesMemory, "memory", false, "back an empty dir with memory")
+	volumesSetCmd.Flags().StringVar(&volumesConfigMap, "config-map", "", "mount the keys of a ConfigMap as files")
+	volumesSetCmd.Flags().StringVar(&volumesSecret, "secret", "", "mount the keys of a Secret as files")
+	addWaitFlags(volumesSetCmd)
+	addChangeFlags(volumesSetCmd)
+	rootCmd.AddCommand(volumesSetCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/rollout"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+var waitForRollout bool
+var waitTimeout time.Duration
+
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&waitForRollout, "wait", false, "wait for the change to be rolled out")
+	cmd.Flags().DurationVar(&waitTimeout, "timeout", 5*time.Minute, "how long to wait for the rollout with --wait")
+}
+
+func newWaiter(ns string) *rollout.Waiter {
+	return &rollout.Waiter{
+		Feisty:    feistyClient,
+		Kube:      kubeClient,
+		Namespace: ns,
+		Out:       os.Stdout,
+	}
+}
+
+// waitForApp waits for the controller to reconcile the updated application
+// and for the resulting deployment rollout to finish
+func waitForApp(ns string, app *v1alpha1.Application) error {
+	waiter := newWaiter(ns)
+	deadline := time.Now().Add(waitTimeout)
+
+	reconciled, err := waiter.WaitForGeneration(app.Name, app.Generation, deadline)
+	if err != nil {
+		return err
+	}
+
+	return waiter.WaitForRollout(reconciled, deadline)
+}
+
+// waitForConfig waits for a config change to reach the application and for
+// the resulting deployment rollout to finish
+func waitForConfig(ns string, previousRef string) error {
+	waiter := newWaiter(ns)
+	deadline := time.Now().Add(waitTimeout)
+
+	app, err := waiter.WaitForConfigRef(appName, previousRef, deadline)
+	if err != nil {
+		return err
+	}
+
+	reconciled, err := waiter.WaitForGeneration(app.Name, app.Generation, deadline)
+	if err != nil {
+		return err
+	}
+
+	return waiter.WaitForRollout(reconciled, deadline)
+}
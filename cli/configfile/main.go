@@ -0,0 +1,277 @@
+package configfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatShell  = "shell"
+)
+
+var Formats = []string{FormatDotenv, FormatJSON, FormatYAML, FormatShell}
+
+// keys end up as keys on a Secret, so they're held to the same rules
+var keyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// FormatFromFilename guesses the format of a config file from its extension,
+// falling back to dotenv
+func FormatFromFilename(filename string) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(lower, ".yaml"), strings.HasSuffix(lower, ".yml"):
+		return FormatYAML
+	default:
+		return FormatDotenv
+	}
+}
+
+func Parse(data []byte, format string) (map[string]string, error) {
+	switch format {
+	case FormatDotenv, FormatShell:
+		return ParseDotenv(string(data))
+	case FormatJSON:
+		return parseStructured(data, json.Unmarshal)
+	case FormatYAML:
+		return parseStructured(data, func(data []byte, v interface{}) error {
+			return yaml.Unmarshal(data, v)
+		})
+	}
+
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+func parseStructured(data []byte, unmarshal func([]byte, interface{}) error) (map[string]string, error) {
+	raw := map[string]interface{}{}
+	if err := unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	kv := map[string]string{}
+	for k, v := range raw {
+		if !keyPattern.MatchString(k) {
+			return nil, fmt.Errorf("invalid key %q", k)
+		}
+
+		switch val := v.(type) {
+		case string:
+			kv[k] = val
+		case nil:
+			kv[k] = ""
+		case bool, float64:
+			kv[k] = fmt.Sprintf("%v", val)
+		default:
+			return nil, fmt.Errorf("value for %s must be a string, number or boolean", k)
+		}
+	}
+
+	return kv, nil
+}
+
+// ParseDotenv parses the contents of a .env file. Lines may be prefixed with
+// export, values may be single quoted (taken literally), double quoted
+// (supporting \n, \t, \", \\ and \$ escapes) or bare. Quoted values may span
+// multiple lines.
+func ParseDotenv(data string) (map[string]string, error) {
+	kv := map[string]string{}
+	data = strings.Replace(data, "\r\n", "\n", -1)
+
+	line := 1
+	pos := 0
+	for pos < len(data) {
+		end := strings.IndexByte(data[pos:], '\n')
+		if end == -1 {
+			end = len(data)
+		} else {
+			end += pos
+		}
+
+		current := strings.TrimSpace(data[pos:end])
+		if current == "" || strings.HasPrefix(current, "#") {
+			pos = end + 1
+			line++
+			continue
+		}
+
+		if strings.HasPrefix(current, "export ") || strings.HasPrefix(current, "export\t") {
+			current = strings.TrimSpace(current[len("export"):])
+		}
+
+		i := strings.Index(current, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: missing =", line)
+		}
+
+		key := strings.TrimSpace(current[:i])
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", line, key)
+		}
+
+		// work out where the value starts in data so quoted values can run past
+		// the end of this line
+		valueStart := pos + strings.Index(data[pos:end], "=") + 1
+		for valueStart < end && (data[valueStart] == ' ' || data[valueStart] == '\t') {
+			valueStart++
+		}
+
+		var value string
+		if valueStart < len(data) && (data[valueStart] == '"' || data[valueStart] == '\'') {
+			quoted, closed, err := parseQuoted(data, valueStart)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+
+			// the shell format writes a single quote in a single quoted value as '\''
+			for data[valueStart] == '\'' && strings.HasPrefix(data[closed:], `\''`) {
+				more, next, err := parseQuoted(data, closed+2)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+
+				quoted += "'" + more
+				closed = next
+			}
+
+			end = len(data)
+			if nl := strings.IndexByte(data[closed:], '\n'); nl != -1 {
+				end = closed + nl
+			}
+
+			rest := strings.TrimSpace(data[closed:end])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("line %d: unexpected characters after quoted value", line)
+			}
+
+			line += strings.Count(data[pos:end], "\n")
+			value = quoted
+		} else {
+			value = data[valueStart:end]
+			if comment := strings.Index(value, " #"); comment != -1 {
+				value = value[:comment]
+			}
+
+			value = strings.TrimSpace(value)
+		}
+
+		kv[key] = value
+		pos = end + 1
+		line++
+	}
+
+	return kv, nil
+}
+
+// parseQuoted reads a quoted value starting at data[start] and returns the
+// unquoted value and the index just after the closing quote
+func parseQuoted(data string, start int) (string, int, error) {
+	quote := data[start]
+	var sb strings.Builder
+
+	for i := start + 1; i < len(data); i++ {
+		c := data[i]
+		if c == quote {
+			return sb.String(), i + 1, nil
+		}
+
+		if quote == '"' && c == '\\' && i+1 < len(data) {
+			i++
+			switch data[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '"', '\\', '$':
+				sb.WriteByte(data[i])
+			case '\n':
+				// line continuation
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(data[i])
+			}
+
+			continue
+		}
+
+		sb.WriteByte(c)
+	}
+
+	return "", 0, fmt.Errorf("unterminated %c quoted value", quote)
+}
+
+func sortedKeys(kv map[string]string) []string {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func Format(kv map[string]string, format string) ([]byte, error) {
+	if kv == nil {
+		kv = map[string]string{}
+	}
+
+	switch format {
+	case FormatDotenv:
+		var sb strings.Builder
+		for _, k := range sortedKeys(kv) {
+			sb.WriteString(k + "=" + dotenvQuote(kv[k]) + "\n")
+		}
+
+		return []byte(sb.String()), nil
+	case FormatShell:
+		var sb strings.Builder
+		for _, k := range sortedKeys(kv) {
+			sb.WriteString("export " + k + "=" + shellQuote(kv[k]) + "\n")
+		}
+
+		return []byte(sb.String()), nil
+	case FormatJSON:
+		out, err := json.MarshalIndent(kv, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(out, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(kv)
+	}
+
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+func dotenvQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\"'\\$#=") {
+		return value
+	}
+
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		"\"", "\\\"",
+		"$", "\\$",
+		"\n", "\\n",
+		"\r", "\\r",
+		"\t", "\\t",
+	)
+
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
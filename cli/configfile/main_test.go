@@ -0,0 +1,116 @@
+package configfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", data: "", want: map[string]string{}},
+		{name: "bare values", data: "A=1\nB = two words \n", want: map[string]string{"A": "1", "B": "two words"}},
+		{name: "comments and blank lines", data: "# comment\n\nA=1 # trailing\nB=x#y\n", want: map[string]string{"A": "1", "B": "x#y"}},
+		{name: "export prefix", data: "export A=1\nexport\tB=2\n", want: map[string]string{"A": "1", "B": "2"}},
+		{name: "windows line endings", data: "A=1\r\nB=2\r\n", want: map[string]string{"A": "1", "B": "2"}},
+		{name: "single quotes are literal", data: `A='$HOME \n'`, want: map[string]string{"A": `$HOME \n`}},
+		{name: "double quote escapes", data: `A="line\nnext \"q\" \$HOME \\"`, want: map[string]string{"A": "line\nnext \"q\" $HOME \\"}},
+		{name: "multiline quoted value", data: "A=\"one\ntwo\"\nB=3\n", want: map[string]string{"A": "one\ntwo", "B": "3"}},
+		{name: "comment after quoted value", data: `A="x" # comment`, want: map[string]string{"A": "x"}},
+		{name: "shell quoted single quote", data: `A='it'\''s'`, want: map[string]string{"A": "it's"}},
+		{name: "empty value", data: "A=\n", want: map[string]string{"A": ""}},
+		{name: "missing =", data: "A\n", wantErr: true},
+		{name: "invalid key", data: "A B=1\n", wantErr: true},
+		{name: "unterminated quote", data: "A=\"open\n", wantErr: true},
+		{name: "text after quoted value", data: `A="x" y`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDotenv(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStructured(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		format  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "json", data: `{"A": "1", "B": 2, "C": true, "D": null}`, format: FormatJSON, want: map[string]string{"A": "1", "B": "2", "C": "true", "D": ""}},
+		{name: "yaml", data: "A: one\nB: 2.5\n", format: FormatYAML, want: map[string]string{"A": "one", "B": "2.5"}},
+		{name: "nested value", data: `{"A": {"B": "1"}}`, format: FormatJSON, wantErr: true},
+		{name: "invalid key", data: `{"A B": "1"}`, format: FormatJSON, wantErr: true},
+		{name: "unsupported format", data: "", format: "toml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	kv := map[string]string{
+		"PLAIN":     "value",
+		"EMPTY":     "",
+		"SPACES":    "two words",
+		"QUOTES":    `say "hi" and 'bye'`,
+		"MULTILINE": "one\ntwo\n\tthree",
+		"SHELL":     `$HOME \ # not a comment`,
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			data, err := Format(kv, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Parse(data, format)
+			if err != nil {
+				t.Fatalf("could not parse %s: %v", data, err)
+			}
+
+			if !reflect.DeepEqual(got, kv) {
+				t.Errorf("got %q, want %q", got, kv)
+			}
+		})
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	for filename, want := range map[string]string{
+		"config.json": FormatJSON,
+		"config.YAML": FormatYAML,
+		"config.yml":  FormatYAML,
+		".env":        FormatDotenv,
+		"config":      FormatDotenv,
+	} {
+		if got := FormatFromFilename(filename); got != want {
+			t.Errorf("FormatFromFilename(%q) = %s, want %s", filename, got, want)
+		}
+	}
+}
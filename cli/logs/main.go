@@ -0,0 +1,210 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mrferos/feisty/cli/client"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+var colors = []string{"\x1b[36m", "\x1b[33m", "\x1b[32m", "\x1b[35m", "\x1b[34m", "\x1b[31m", "\x1b[96m", "\x1b[93m", "\x1b[92m", "\x1b[95m"}
+
+const colorReset = "\x1b[0m"
+
+type Options struct {
+	// Follow keeps streaming and picks up pods started after the call
+	Follow bool
+	// Since only returns lines newer than this, 0 returns everything
+	Since time.Duration
+	// Lines is how many lines to return per container before following, -1 returns everything
+	Lines int64
+	// Process limits the pods to a process type, e.g. web
+	Process string
+	// Revision limits the pods to a revision, e.g. v5
+	Revision string
+	Color    bool
+}
+
+type Streamer struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Out       io.Writer
+
+	outLock sync.Mutex
+	streams map[string]bool
+	lock    sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// Stream writes the logs of every container in every pod of the application
+// to Out, each line prefixed with the pod and container it came from. When
+// following, Stream returns once ctx is done.
+func (s *Streamer) Stream(ctx context.Context, appName string, opts Options) error {
+	s.streams = map[string]bool{}
+	listOpts := metav1.ListOptions{LabelSelector: client.AppPodSelector(appName, opts.Process, opts.Revision)}
+
+	pods, err := s.Client.CoreV1().Pods(s.Namespace).List(listOpts)
+	if err != nil {
+		return err
+	}
+
+	if len(pods.Items) == 0 && !opts.Follow {
+		return fmt.Errorf("no pods found for %s", appName)
+	}
+
+	for i := range pods.Items {
+		s.startPod(ctx, &pods.Items[i], opts)
+	}
+
+	if opts.Follow {
+		listOpts.ResourceVersion = pods.ResourceVersion
+		if err := s.watch(ctx, listOpts, opts); err != nil {
+			return err
+		}
+	}
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *Streamer) watch(ctx context.Context, listOpts metav1.ListOptions, opts Options) error {
+	watcher, err := s.Client.CoreV1().Pods(s.Namespace).Watch(listOpts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		watcher.Stop()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				// the api server closes watches periodically, so start a new one
+				listOpts.ResourceVersion = ""
+				watcher, err = s.Client.CoreV1().Pods(s.Namespace).Watch(listOpts)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if event.Type != watch.Added && event.Type != watch.Modified {
+				continue
+			}
+
+			if pod, ok := event.Object.(*v1.Pod); ok {
+				s.startPod(ctx, pod, opts)
+			}
+		}
+	}
+}
+
+// startPod starts streaming every running container of a pod that isn't
+// being streamed already
+func (s *Streamer) startPod(ctx context.Context, pod *v1.Pod, opts Options) {
+	for _, status := range pod.Status.ContainerStatuses {
+		// a terminated container's logs are only worth fetching once, following
+		// them would repeat them on every pod update
+		if status.State.Running == nil && (opts.Follow || status.State.Terminated == nil) {
+			continue
+		}
+
+		key := pod.Name + "/" + status.Name
+		s.lock.Lock()
+		if s.streams[key] {
+			s.lock.Unlock()
+			continue
+		}
+
+		s.streams[key] = true
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go func(podName, container string) {
+			defer s.wg.Done()
+			s.streamContainer(ctx, podName, container, opts)
+
+			// let a later watch event restart the stream, e.g. after a container restart
+			if opts.Follow {
+				s.lock.Lock()
+				delete(s.streams, podName+"/"+container)
+				s.lock.Unlock()
+			}
+		}(pod.Name, status.Name)
+	}
+}
+
+func (s *Streamer) streamContainer(ctx context.Context, podName string, container string, opts Options) {
+	logOpts := v1.PodLogOptions{
+		Container: container,
+		Follow:    opts.Follow,
+	}
+
+	if opts.Lines >= 0 {
+		lines := opts.Lines
+		logOpts.TailLines = &lines
+	}
+
+	if opts.Since > 0 {
+		seconds := int64(opts.Since.Seconds())
+		logOpts.SinceSeconds = &seconds
+	}
+
+	prefix := podName + " " + container + " | "
+	if opts.Color {
+		prefix = colorFor(podName+container) + prefix + colorReset
+	}
+
+	stream, err := s.Client.CoreV1().Pods(s.Namespace).GetLogs(podName, &logOpts).Stream()
+	if err != nil {
+		s.writeLine(prefix, fmt.Sprintf("could not stream logs: %v", err))
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	defer stream.Close()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s.writeLine(prefix, scanner.Text())
+	}
+}
+
+func (s *Streamer) writeLine(prefix string, line string) {
+	s.outLock.Lock()
+	defer s.outLock.Unlock()
+
+	_, _ = fmt.Fprintln(s.Out, prefix+line)
+}
+
+func colorFor(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	return colors[h.Sum32()%uint32(len(colors))]
+}
@@ -0,0 +1,347 @@
+package rollout
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/cli/client"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	pollInterval = 2 * time.Second
+	logLines     = int64(20)
+
+	// container waiting reasons that won't fix themselves without a new deploy
+	failedReasons = map[string]bool{
+		"CrashLoopBackOff":           true,
+		"ErrImagePull":               true,
+		"ImagePullBackOff":           true,
+		"InvalidImageName":           true,
+		"CreateContainerConfigError": true,
+		"CreateContainerError":       true,
+	}
+)
+
+type Waiter struct {
+	Feisty    client.FeistyV1Alpha1Interface
+	Kube      kubernetes.Interface
+	Namespace string
+	Out       io.Writer
+
+	lastMessage string
+}
+
+// FailedError is returned when the rollout failed rather than timed out
+type FailedError struct {
+	Reason string
+	Pod    string
+	Logs   string
+}
+
+func (e *FailedError) Error() string {
+	msg := "rollout failed: " + e.Reason
+	if e.Pod != "" {
+		msg += "\n\nlast logs from " + e.Pod + ":\n" + e.Logs
+	}
+
+	return msg
+}
+
+func (w *Waiter) progress(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if msg == w.lastMessage {
+		return
+	}
+
+	w.lastMessage = msg
+	_, _ = fmt.Fprintln(w.Out, msg)
+}
+
+// WaitForApplication watches the application until done returns true for it
+func (w *Waiter) WaitForApplication(appName string, deadline time.Time, done func(app *v1alpha1.Application) bool) (*v1alpha1.Application, error) {
+	app, err := w.Feisty.Applications(w.Namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if done(app) {
+		return app, nil
+	}
+
+	watcher, err := w.Feisty.Applications(w.Namespace).Watch(metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", appName).String(),
+		ResourceVersion: app.ResourceVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer watcher.Stop()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return nil, fmt.Errorf("timed out waiting for %s to be reconciled", appName)
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return w.WaitForApplication(appName, deadline, done)
+			}
+
+			if event.Type == watch.Deleted {
+				return nil, fmt.Errorf("%s was deleted", appName)
+			}
+
+			if app, ok := event.Object.(*v1alpha1.Application); ok && done(app) {
+				return app, nil
+			}
+		}
+	}
+}
+
+// WaitForGeneration waits for the controller to reconcile the given
+// generation of the application
+func (w *Waiter) WaitForGeneration(appName string, generation int64, deadline time.Time) (*v1alpha1.Application, error) {
+	w.progress("Waiting for %s to be reconciled...", appName)
+
+	return w.WaitForApplication(appName, deadline, func(app *v1alpha1.Application) bool {
+		return app.Status.ObservedGeneration >= generation
+	})
+}
+
+// WaitForConfigRef waits for the application to be pointed at a new config
+// secret after its config was changed
+func (w *Waiter) WaitForConfigRef(appName string, previousRef string, deadline time.Time) (*v1alpha1.Application, error) {
+	w.progress("Waiting for the config of %s to be applied...", appName)
+
+	return w.WaitForApplication(appName, deadline, func(app *v1alpha1.Application) bool {
+		return app.Spec.AppConfigRef != "" && app.Spec.AppConfigRef != previousRef
+	})
+}
+
+// WaitForRollout waits until every replica of the application's deployment
+// runs the latest pod template and is available. Under the bluegreen and
+// canary strategies it also waits for the new revision to get all the
+// traffic, the application is read again on every poll to follow it.
+func (w *Waiter) WaitForRollout(app *v1alpha1.Application, deadline time.Time) error {
+	if app.Spec.Image == "" {
+		w.progress("%s has no image, there's nothing to roll out", app.Name)
+		return nil
+	}
+
+	for {
+		done, err := w.checkApplication(app)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			w.progress("%s was successfully rolled out", app.Name)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the rollout of %s: %s", app.Name, w.lastMessage)
+		}
+
+		time.Sleep(pollInterval)
+
+		app, err = w.Feisty.Applications(w.Namespace).Get(app.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// checkApplication tells whether the application's current revision is
+// rolled out and serves all the traffic
+func (w *Waiter) checkApplication(app *v1alpha1.Application) (bool, error) {
+	if c := app.Status.Canary; c != nil {
+		switch {
+		case c.AbortedDeployment != "":
+			return false, &FailedError{Reason: c.Message}
+		case c.CanaryDeployment != "":
+			// the controller aborts a canary whose pods fail
+			w.progress("Canary %s at %d%%: %s...", c.CanaryDeployment, c.Weight, c.Message)
+			return false, nil
+		case c.StableDeployment == app.Name:
+			w.progress("Waiting for the deployment of %s to be moved to its revision's own...", app.Name)
+			return false, nil
+		}
+	}
+
+	if bg := app.Status.BlueGreen; bg != nil {
+		switch {
+		case bg.PreviewDeployment != "":
+			done, err := w.checkRollout(app.Name, bg.PreviewDeployment)
+			if err != nil || !done {
+				return false, err
+			}
+
+			w.progress("Waiting for traffic to be switched to %s...", bg.PreviewDeployment)
+			return false, nil
+		case bg.ActiveDeployment == app.Name:
+			w.progress("Waiting for the deployment of %s to be moved to its revision's own...", app.Name)
+			return false, nil
+		}
+	}
+
+	return w.checkRollout(app.Name, app.Status.DeploymentName(app.Name))
+}
+
+func (w *Waiter) checkRollout(appName string, deploymentName string) (bool, error) {
+	deployment, err := w.Kube.AppsV1().Deployments(w.Namespace).Get(deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		w.progress("Waiting for the deployment of %s to be updated...", appName)
+		return false, nil
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, w.failure(deployment, condition.Message)
+		}
+	}
+
+	if err := w.checkPods(deployment); err != nil {
+		return false, err
+	}
+
+	replicas := int32(0)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		w.progress("%d of %d replicas updated...", status.UpdatedReplicas, replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		w.progress("%d old replicas pending termination...", status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		w.progress("%d of %d updated replicas available...", status.AvailableReplicas, status.UpdatedReplicas)
+	default:
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// checkPods fails the rollout when a pod of the new replica set is stuck
+// pulling its image or crash looping
+func (w *Waiter) checkPods(deployment *appsv1.Deployment) error {
+	pods, err := w.newPods(deployment)
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting == nil || !failedReasons[status.State.Waiting.Reason] {
+				continue
+			}
+
+			reason := fmt.Sprintf("%s in %s: %s", status.State.Waiting.Reason, pod.Name, status.State.Waiting.Message)
+
+			return &FailedError{
+				Reason: reason,
+				Pod:    pod.Name,
+				Logs:   w.lastLogs(pod.Name, status.Name, status.RestartCount > 0),
+			}
+		}
+	}
+
+	return nil
+}
+
+// newPods returns the pods belonging to the deployment's newest replica set
+func (w *Waiter) newPods(deployment *appsv1.Deployment) ([]v1.Pod, error) {
+	selector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	replicaSets, err := w.Kube.AppsV1().ReplicaSets(w.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	revision := deployment.Annotations["deployment.kubernetes.io/revision"]
+	templateHash := ""
+	for _, rs := range replicaSets.Items {
+		if metav1.IsControlledBy(&rs, deployment) && rs.Annotations["deployment.kubernetes.io/revision"] == revision {
+			templateHash = rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		}
+	}
+
+	if templateHash == "" {
+		return nil, nil
+	}
+
+	pods, err := w.Kube.CoreV1().Pods(w.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector + "," + appsv1.DefaultDeploymentUniqueLabelKey + "=" + templateHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+func (w *Waiter) failure(deployment *appsv1.Deployment, reason string) error {
+	failed := &FailedError{Reason: reason}
+
+	pods, err := w.newPods(deployment)
+	if err != nil {
+		return failed
+	}
+
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if !status.Ready {
+				failed.Pod = pod.Name
+				failed.Logs = w.lastLogs(pod.Name, status.Name, status.RestartCount > 0)
+
+				return failed
+			}
+		}
+	}
+
+	return failed
+}
+
+func (w *Waiter) lastLogs(podName string, container string, previous bool) string {
+	lines := logLines
+	stream, err := w.Kube.CoreV1().Pods(w.Namespace).GetLogs(podName, &v1.PodLogOptions{
+		Container: container,
+		TailLines: &lines,
+		Previous:  previous,
+	}).Stream()
+	if err != nil {
+		return fmt.Sprintf("could not fetch logs: %v", err)
+	}
+
+	defer stream.Close()
+
+	logs := ""
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		logs += scanner.Text() + "\n"
+	}
+
+	if logs == "" {
+		return "(no logs)\n"
+	}
+
+	return logs
+}
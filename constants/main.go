@@ -2,4 +2,37 @@ package constants
 
 var (
 	FeistyAnnotationPrefix = "paas.feisty.dev/"
+
+	// AppLabel is set on every pod of an application and used as its selector
+	AppLabel = "app"
+	// ProcessTypeLabel holds the process type (e.g. web) a pod runs
+	ProcessTypeLabel = FeistyAnnotationPrefix + "process-type"
+	// RevisionLabel holds the revision (e.g. v5) a pod was created from
+	RevisionLabel = FeistyAnnotationPrefix + "revision"
+	// RevisionHashLabel holds the hash of the app and config specs a revision
+	// was created from
+	RevisionHashLabel = FeistyAnnotationPrefix + "revision-hash"
+	// MaintenanceLabel holds the application a maintenance backend pod serves
+	MaintenanceLabel = FeistyAnnotationPrefix + "maintenance"
+	// CanaryResumeAnnotation resumes a paused canary, its value names the canary
+	// Deployment and the step to resume, e.g. app-v5/1
+	CanaryResumeAnnotation = FeistyAnnotationPrefix + "canary-resume"
+
+	// ContainersAnnotation lists the sidecars the operator added to a pod
+	// template, other containers besides the application's were added by
+	// someone else and are left alone
+	ContainersAnnotation = FeistyAnnotationPrefix + "containers"
+	// InitContainersAnnotation lists the init containers the operator added to
+	// a pod template
+	InitContainersAnnotation = FeistyAnnotationPrefix + "init-containers"
+	// VolumesAnnotation lists the volumes the operator added to a pod template
+	VolumesAnnotation = FeistyAnnotationPrefix + "volumes"
+	// VolumeLabel holds the volume of an application a PersistentVolumeClaim
+	// was created for
+	VolumeLabel = FeistyAnnotationPrefix + "volume"
+	// SchedulingAnnotation holds the scheduling defaults of the applications in
+	// a namespace as JSON, e.g. {"nodeSelector": {"pool": "production"}}
+	SchedulingAnnotation = FeistyAnnotationPrefix + "scheduling"
+
+	DefaultProcessType = "web"
 )
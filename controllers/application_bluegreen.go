@@ -0,0 +1,451 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	"github.com/mrferos/feisty/revisions"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var defaultKeepPreviousFor = 30 * time.Minute
+
+func keepPreviousFor(app feistyv1alpha1.Application) time.Duration {
+	if app.Spec.BlueGreen != nil && app.Spec.BlueGreen.KeepPreviousFor != nil {
+		return app.Spec.BlueGreen.KeepPreviousFor.Duration
+	}
+
+	return defaultKeepPreviousFor
+}
+
+// deploymentSelector returns the selector of one of the application's
+// Deployments. A bluegreen or canary Deployment is named after the revision it was
+// created for and only selects that revision's pods, the rolling Deployment
+// selects every pod of the application.
+func deploymentSelector(app feistyv1alpha1.Application, deploymentName string) map[string]string {
+	selector := getAppLabels(app)
+	if deploymentName != app.Name {
+		selector[constants.RevisionLabel] = strings.TrimPrefix(deploymentName, app.Name+"-")
+	}
+
+	return selector
+}
+
+// serviceSelector returns the pods the Service sends traffic to, which are
+// the active bluegreen Deployment's while there is one. A canary gets its
+// share of the traffic through the Service unless the ingress routes it.
+func serviceSelector(app feistyv1alpha1.Application, status feistyv1alpha1.ApplicationStatus) map[string]string {
+	if status.BlueGreen != nil && status.BlueGreen.ActiveDeployment != "" {
+		return deploymentSelector(app, status.BlueGreen.ActiveDeployment)
+	}
+
+	if status.Canary != nil && status.Canary.StableDeployment != "" && canaryOptions(app).TrafficRouting == feistyv1alpha1.CanaryNginxRouting {
+		return deploymentSelector(app, status.Canary.StableDeployment)
+	}
+
+	return getAppLabels(app)
+}
+
+// servingSelector returns the pods of the Deployment serving traffic, which
+// are the active bluegreen or stable canary Deployment's while there is one
+func servingSelector(app feistyv1alpha1.Application, status feistyv1alpha1.ApplicationStatus) map[string]string {
+	if status.BlueGreen != nil && status.BlueGreen.ActiveDeployment != "" {
+		return deploymentSelector(app, status.BlueGreen.ActiveDeployment)
+	}
+
+	if status.Canary != nil && status.Canary.StableDeployment != "" {
+		return deploymentSelector(app, status.Canary.StableDeployment)
+	}
+
+	return getAppLabels(app)
+}
+
+// listDeployments returns the Deployments running the application's pods by
+// name, leaving out the maintenance backend
+func (r *ApplicationReconciler) listDeployments(app feistyv1alpha1.Application, ctx context.Context) (map[string]v1.Deployment, error) {
+	var deployments v1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(app.Namespace)); err != nil {
+		return nil, err
+	}
+
+	owned := map[string]v1.Deployment{}
+	for _, deployment := range deployments.Items {
+		if metav1.IsControlledBy(&deployment, &app) && deployment.Name != maintenanceName(app) {
+			owned[deployment.Name] = deployment
+		}
+	}
+
+	return owned, nil
+}
+
+// servingDeployment returns the Deployment serving traffic under the strategy
+// the application used before, empty when it has none
+func servingDeployment(app feistyv1alpha1.Application, status feistyv1alpha1.ApplicationStatus, deployments map[string]v1.Deployment) string {
+	names := []string{app.Name}
+	if status.Canary != nil {
+		names = append([]string{status.Canary.StableDeployment}, names...)
+	}
+
+	if status.BlueGreen != nil {
+		names = append([]string{status.BlueGreen.ActiveDeployment}, names...)
+	}
+
+	for _, name := range names {
+		if _, ok := deployments[name]; ok && name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// runsApplication tells whether a Deployment already runs the pods the
+// application asks for, so it doesn't need replacing
+func runsApplication(app feistyv1alpha1.Application, deployment v1.Deployment) bool {
+	template := deployment.Spec.Template.DeepCopy()
+	applyPodTemplate(app, template)
+
+	return !podTemplateChanged(deployment.Spec.Template, *template)
+}
+
+// upsertRevisionDeployment creates or updates the Deployment named after a
+// revision that runs the application's pods
+func (r *ApplicationReconciler) upsertRevisionDeployment(app feistyv1alpha1.Application, deployments map[string]v1.Deployment, name string, replicas *int32, current revisions.NumberedRevision, ctx context.Context) (v1.Deployment, error) {
+	deployment, exists := deployments[name]
+	if !exists {
+		deployment = v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: app.Namespace,
+				Labels:    getAppLabels(app),
+			},
+			Spec: v1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: deploymentSelector(app, name),
+				},
+				Template: v12.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				},
+			},
+		}
+	}
+
+	before := deployment.Spec.Template.DeepCopy()
+	applyPodTemplate(app, &deployment.Spec.Template)
+	applyDeploymentSpec(app, &deployment.Spec)
+	deployment.Spec.Replicas = replicas
+
+	// a reused Deployment keeps the revision labels its selector matches on
+	if !exists || (name == revisions.RevisionName(app.Name, current.Number) && podTemplateChanged(*before, deployment.Spec.Template)) {
+		if err := labelRevision(&deployment.Spec.Template, current); err != nil {
+			return deployment, err
+		}
+	}
+
+	if !exists {
+		_ = ctrl.SetControllerReference(&app, &deployment, r.Scheme)
+		return deployment, r.Create(ctx, &deployment)
+	}
+
+	return deployment, r.Update(ctx, &deployment)
+}
+
+// moveRollingDeployment copies the rolling Deployment, whose selector matches
+// every pod of the application, to a Deployment of its revision's own so the
+// Service doesn't send traffic to the pods rolled out next to it. It returns
+// the copy's name once it's ready to take over, empty until then.
+func (r *ApplicationReconciler) moveRollingDeployment(app feistyv1alpha1.Application, deployments map[string]v1.Deployment, ctx context.Context) (string, error) {
+	rolling := deployments[app.Name]
+	label := rolling.Spec.Template.Labels[constants.RevisionLabel]
+	// the pods were rolled out before they were labelled with their revision
+	if label == "" {
+		label = "v0"
+	}
+
+	name := app.Name + "-" + label
+	deployment, exists := deployments[name]
+	if !exists {
+		template := rolling.Spec.Template.DeepCopy()
+		template.Name = name
+		template.Labels[constants.RevisionLabel] = label
+		deployment = v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: app.Namespace,
+				Labels:    getAppLabels(app),
+			},
+			Spec: v1.DeploymentSpec{
+				Replicas: rolling.Spec.Replicas,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: deploymentSelector(app, name),
+				},
+				Template: *template,
+				Strategy: rolling.Spec.Strategy,
+			},
+		}
+
+		_ = ctrl.SetControllerReference(&app, &deployment, r.Scheme)
+		return "", r.Create(ctx, &deployment)
+	}
+
+	if phase, _ := deploymentPhase(deployment); phase != feistyv1alpha1.RevisionSucceeded {
+		return "", nil
+	}
+
+	return name, nil
+}
+
+// scaleDeployment sets the replicas of a Deployment without touching its pods
+func (r *ApplicationReconciler) scaleDeployment(deployment v1.Deployment, replicas int32, ctx context.Context) (v1.Deployment, error) {
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == replicas {
+		return deployment, nil
+	}
+
+	deployment.Spec.Replicas = &replicas
+
+	return deployment, r.Update(ctx, &deployment)
+}
+
+// scaleServingDeployment scales the Deployment serving traffic without
+// touching its pods, it returns whether there is one. When the replicas are
+// held, e.g. because the volumes can't be shared by the requested count, the
+// Deployment is only scaled down, or back up to a single replica after sleeping.
+func (r *ApplicationReconciler) scaleServingDeployment(app feistyv1alpha1.Application, status feistyv1alpha1.ApplicationStatus, hold bool, ctx context.Context) (bool, error) {
+	deployments, err := r.listDeployments(app, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	name := servingDeployment(app, status, deployments)
+	if name == "" {
+		return false, nil
+	}
+
+	deployment := deployments[name]
+	replicas := *getReplicas(app)
+	if hold {
+		limit := int32(1)
+		if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas > limit {
+			limit = *deployment.Spec.Replicas
+		}
+
+		if replicas > limit {
+			replicas = limit
+		}
+	}
+
+	_, err = r.scaleDeployment(deployment, replicas, ctx)
+
+	return true, err
+}
+
+// deleteDeployments deletes the application's Deployments that aren't kept
+func (r *ApplicationReconciler) deleteDeployments(deployments map[string]v1.Deployment, keep []string, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	kept := map[string]bool{}
+	for _, name := range keep {
+		kept[name] = true
+	}
+
+	for name, deployment := range deployments {
+		if kept[name] {
+			continue
+		}
+
+		log.Info("Deleting deployment", "deploymentName", name)
+		if err := r.Delete(ctx, &deployment); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Could not delete deployment", "deploymentName", name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// upsertBlueGreen rolls out the current revision with a Deployment of its
+// own, moving the Service over to it once every replica is available. The
+// Deployment traffic was moved away from is kept until the KeepPreviousFor
+// delay passed, a revision that runs the same pods as a Deployment that's
+// still around (e.g. after a scale or a switch back) reuses it.
+func (r *ApplicationReconciler) upsertBlueGreen(app feistyv1alpha1.Application, current *revisions.NumberedRevision, status *feistyv1alpha1.ApplicationStatus, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	// without a revision there's nothing to name the Deployment after
+	if current == nil {
+		return ctrl.Result{}, nil
+	}
+
+	deployments, err := r.listDeployments(app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to list deployments")
+		return ctrl.Result{}, err
+	}
+
+	if status.BlueGreen == nil {
+		status.BlueGreen = &feistyv1alpha1.BlueGreenStatus{}
+	}
+
+	bg := status.BlueGreen
+	// the Deployment of the previous strategy keeps serving traffic until the
+	// first switch
+	if bg.ActiveDeployment == "" {
+		bg.ActiveDeployment = servingDeployment(app, *status, deployments)
+	}
+
+	if status.Canary != nil {
+		if err := r.removeCanaryRouting(app, ctx); err != nil {
+			log.Error(err, "Could not remove canary routing")
+			return ctrl.Result{}, err
+		}
+
+		status.Canary = nil
+	}
+
+	// nothing is rolled out next to the rolling Deployment, the Service would
+	// send it traffic before the switch
+	if bg.ActiveDeployment == app.Name {
+		moved, err := r.moveRollingDeployment(app, deployments, ctx)
+		if err != nil {
+			log.Error(err, "Could not move the rolling deployment")
+			return ctrl.Result{}, err
+		}
+
+		if moved == "" {
+			return ctrl.Result{}, nil
+		}
+
+		bg.ActiveDeployment = moved
+	}
+
+	// the Deployment named after the current revision is updated in place when
+	// its pods change without a new revision, e.g. with the namespace's
+	// scheduling defaults
+	target := revisions.RevisionName(app.Name, current.Number)
+	for _, name := range []string{bg.ActiveDeployment, bg.PreviewDeployment, bg.PreviousDeployment} {
+		if deployment, ok := deployments[name]; ok && runsApplication(app, deployment) {
+			target = name
+			break
+		}
+	}
+
+	deployment, err := r.upsertRevisionDeployment(app, deployments, target, getReplicas(app), *current, ctx)
+	if err != nil {
+		log.Error(err, "Could not upsert deployment", "deploymentName", target)
+		return ctrl.Result{}, err
+	}
+
+	phase, _ := deploymentPhase(deployment)
+	switch {
+	case target == bg.ActiveDeployment:
+		bg.PreviewDeployment = ""
+	case bg.ActiveDeployment == "":
+		// nothing is serving traffic yet, so there's nothing to wait for
+		bg.ActiveDeployment = target
+		bg.PreviewDeployment = ""
+	case phase == feistyv1alpha1.RevisionSucceeded:
+		log.Info("Switching traffic", "from", bg.ActiveDeployment, "to", target)
+		r.Recorder.Eventf(&app, v12.EventTypeNormal, "Switched", "Switched traffic from %s to %s", bg.ActiveDeployment, target)
+
+		now := metav1.Now()
+		scaleDownTime := metav1.NewTime(now.Add(keepPreviousFor(app)))
+		bg.PreviousDeployment = bg.ActiveDeployment
+		bg.ActiveDeployment = target
+		bg.PreviewDeployment = ""
+		bg.SwitchTime = &now
+		bg.ScaleDownTime = &scaleDownTime
+	default:
+		bg.PreviewDeployment = target
+	}
+
+	result := ctrl.Result{}
+	if bg.PreviousDeployment != "" && bg.ScaleDownTime != nil {
+		if wait := time.Until(bg.ScaleDownTime.Time); wait > 0 {
+			result.RequeueAfter = wait
+		} else {
+			bg.PreviousDeployment = ""
+			bg.ScaleDownTime = nil
+		}
+	}
+
+	// anything else is a previous Deployment that expired or a preview that
+	// was replaced before it became ready
+	if err := r.deleteDeployments(deployments, []string{bg.ActiveDeployment, bg.PreviewDeployment, bg.PreviousDeployment}, req, ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return result, nil
+}
+
+// cleanupDeployments moves an application that switched back to the rolling
+// strategy off its bluegreen or canary Deployments once the rolling one is
+// ready. Until then the rolling Deployment is tracked as a bluegreen preview.
+func (r *ApplicationReconciler) cleanupDeployments(app feistyv1alpha1.Application, status *feistyv1alpha1.ApplicationStatus, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	if status.Canary != nil {
+		if err := r.removeCanaryRouting(app, ctx); err != nil {
+			log.Error(err, "Could not remove canary routing")
+			return err
+		}
+
+		status.BlueGreen = &feistyv1alpha1.BlueGreenStatus{
+			ActiveDeployment: status.Canary.StableDeployment,
+		}
+		status.Canary = nil
+	}
+
+	if status.BlueGreen == nil {
+		return nil
+	}
+
+	var rolling v1.Deployment
+	if err := r.Get(ctx, req.NamespacedName, &rolling); err != nil {
+		log.Error(err, "Unable to fetch deployment")
+		return err
+	}
+
+	if phase, _ := deploymentPhase(rolling); phase != feistyv1alpha1.RevisionSucceeded {
+		status.BlueGreen.PreviewDeployment = app.Name
+		return nil
+	}
+
+	deployments, err := r.listDeployments(app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to list deployments")
+		return err
+	}
+
+	if err := r.deleteDeployments(deployments, []string{app.Name}, req, ctx); err != nil {
+		return err
+	}
+
+	status.BlueGreen = nil
+
+	return nil
+}
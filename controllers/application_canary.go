@@ -0,0 +1,465 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	"github.com/mrferos/feisty/revisions"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// the canary is checked on this interval as restarts don't touch its Deployment
+	canaryCheckInterval = 15 * time.Second
+	defaultMaxRestarts  = 2
+	defaultCanarySteps  = []feistyv1alpha1.CanaryStep{
+		{Weight: 10, Duration: &metav1.Duration{Duration: time.Minute}},
+		{Weight: 50, Duration: &metav1.Duration{Duration: time.Minute}},
+	}
+
+	nginxCanaryAnnotation       = "nginx.ingress.kubernetes.io/canary"
+	nginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+)
+
+func canaryOptions(app feistyv1alpha1.Application) feistyv1alpha1.CanaryOptions {
+	options := feistyv1alpha1.CanaryOptions{}
+	if app.Spec.Canary != nil {
+		options = *app.Spec.Canary.DeepCopy()
+	}
+
+	if len(options.Steps) == 0 {
+		options.Steps = defaultCanarySteps
+	}
+
+	if options.TrafficRouting == "" {
+		options.TrafficRouting = feistyv1alpha1.CanaryReplicaRouting
+	}
+
+	if options.MaxRestarts == nil {
+		options.MaxRestarts = &defaultMaxRestarts
+	}
+
+	return options
+}
+
+func canaryName(app feistyv1alpha1.Application) string {
+	return app.Name + "-canary"
+}
+
+// canaryWeight returns the percentage of traffic the canary gets at a step,
+// all of it once it's past the last one
+func canaryWeight(options feistyv1alpha1.CanaryOptions, step int) int {
+	if step < len(options.Steps) {
+		return options.Steps[step].Weight
+	}
+
+	return 100
+}
+
+// canaryReplicas works out how many of the replicas run the canary to give
+// it the weight of the traffic, it always gets at least one
+func canaryReplicas(replicas int32, weight int) int32 {
+	if replicas == 0 {
+		return 0
+	}
+
+	canary := (replicas*int32(weight) + 99) / 100
+	if canary < 1 {
+		canary = 1
+	}
+
+	if canary > replicas {
+		canary = replicas
+	}
+
+	return canary
+}
+
+// stableCanaryReplicas works out how many replicas the stable Deployment
+// keeps next to a canary running the given replicas. Routing by replicas, the
+// stable Deployment keeps at least one until the canary gets all the traffic,
+// so a canary of a single replica app gets half of it rather than all.
+func stableCanaryReplicas(options feistyv1alpha1.CanaryOptions, replicas int32, canary int32, weight int) int32 {
+	if options.TrafficRouting != feistyv1alpha1.CanaryReplicaRouting {
+		return replicas
+	}
+
+	stable := replicas - canary
+	if stable < 1 && replicas > 0 && weight < 100 {
+		stable = 1
+	}
+
+	return stable
+}
+
+// checkCanary tells whether every replica of the canary is available, or why
+// the canary went bad
+func (r *ApplicationReconciler) checkCanary(app feistyv1alpha1.Application, deployment v1.Deployment, maxRestarts int, ctx context.Context) (bool, string, error) {
+	phase, message := deploymentPhase(deployment)
+	if phase == feistyv1alpha1.RevisionFailed {
+		return false, message, nil
+	}
+
+	var pods v12.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(app.Namespace), client.MatchingLabels(deploymentSelector(app, deployment.Name))); err != nil {
+		return false, "", err
+	}
+
+	restarts := 0
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += int(status.RestartCount)
+		}
+	}
+
+	if restarts > maxRestarts {
+		return false, fmt.Sprintf("the canary containers restarted %d times", restarts), nil
+	}
+
+	return phase == feistyv1alpha1.RevisionSucceeded, "", nil
+}
+
+// upsertCanaryRouting points the canary ingress at the canary pods with the
+// weight of the traffic they get
+func (r *ApplicationReconciler) upsertCanaryRouting(app feistyv1alpha1.Application, canary string, weight int, ctx context.Context) error {
+	name := types.NamespacedName{Namespace: app.Namespace, Name: canaryName(app)}
+
+	var svc v12.Service
+	svcExists := true
+	if err := r.Get(ctx, name, &svc); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		svcExists = false
+		svc = v12.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name.Name,
+				Namespace: app.Namespace,
+			},
+			Spec: v12.ServiceSpec{
+				Ports: []v12.ServicePort{{
+					Name:     "http",
+					Protocol: "TCP",
+					Port:     defaultExposedPort,
+					TargetPort: intstr.IntOrString{
+						IntVal: int32(app.Spec.Port),
+					},
+				}},
+				Type: "ClusterIP",
+			},
+		}
+	}
+
+	svc.Spec.Selector = deploymentSelector(app, canary)
+	if svcExists {
+		if err := r.Update(ctx, &svc); err != nil {
+			return err
+		}
+	} else {
+		_ = ctrl.SetControllerReference(&app, &svc, r.Scheme)
+		if err := r.Create(ctx, &svc); err != nil {
+			return err
+		}
+	}
+
+	var ingress v1beta1.Ingress
+	ingressExists := true
+	if err := r.Get(ctx, name, &ingress); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		ingressExists = false
+		ingress = v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name.Name,
+				Namespace: app.Namespace,
+			},
+		}
+	}
+
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+
+	ingress.Annotations[nginxCanaryAnnotation] = "true"
+	ingress.Annotations[nginxCanaryWeightAnnotation] = strconv.Itoa(weight)
+	ingress.Spec.Rules = ingressRules(app, name.Name)
+
+	if ingressExists {
+		return r.Update(ctx, &ingress)
+	}
+
+	_ = ctrl.SetControllerReference(&app, &ingress, r.Scheme)
+
+	return r.Create(ctx, &ingress)
+}
+
+// removeCanaryRouting deletes the canary ingress and service, if any
+func (r *ApplicationReconciler) removeCanaryRouting(app feistyv1alpha1.Application, ctx context.Context) error {
+	meta := metav1.ObjectMeta{Namespace: app.Namespace, Name: canaryName(app)}
+
+	if err := r.Delete(ctx, &v1beta1.Ingress{ObjectMeta: meta}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	if err := r.Delete(ctx, &v12.Service{ObjectMeta: meta}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upsertCanary rolls out the current revision with a Deployment of its own
+// next to the stable one, moving traffic over to it in steps. The canary is
+// checked at every step and aborted when it can't become ready or its
+// containers keep restarting, it's promoted to stable once it gets all the
+// traffic and is fully available.
+func (r *ApplicationReconciler) upsertCanary(app feistyv1alpha1.Application, current *revisions.NumberedRevision, status *feistyv1alpha1.ApplicationStatus, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	// without a revision there's nothing to name the Deployment after
+	if current == nil {
+		return ctrl.Result{}, nil
+	}
+
+	deployments, err := r.listDeployments(app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to list deployments")
+		return ctrl.Result{}, err
+	}
+
+	if status.Canary == nil {
+		status.Canary = &feistyv1alpha1.CanaryStatus{
+			StableDeployment: servingDeployment(app, *status, deployments),
+		}
+	}
+
+	status.BlueGreen = nil
+	c := status.Canary
+	options := canaryOptions(app)
+	c.Steps = len(options.Steps)
+	currentName := revisions.RevisionName(app.Name, current.Number)
+
+	runs := func(name string) bool {
+		deployment, ok := deployments[name]
+		return ok && name != "" && runsApplication(app, deployment)
+	}
+
+	// the stable Deployment may have been deleted from under us
+	if _, ok := deployments[c.StableDeployment]; !ok {
+		c.StableDeployment = ""
+	}
+
+	// nothing is rolled out next to the rolling Deployment, the Service would
+	// send the canary more than its share of the traffic
+	if c.StableDeployment == app.Name {
+		moved, err := r.moveRollingDeployment(app, deployments, ctx)
+		if err != nil {
+			log.Error(err, "Could not move the rolling deployment")
+			return ctrl.Result{}, err
+		}
+
+		if moved == "" {
+			return ctrl.Result{}, nil
+		}
+
+		c.StableDeployment = moved
+	}
+
+	// pods that change without a new revision, e.g. with the namespace's
+	// scheduling defaults, are updated in place by the Deployment running the
+	// revision rather than canaried against themselves
+	switch {
+	case c.StableDeployment == "" || c.StableDeployment == currentName || runs(c.StableDeployment):
+		if c.CanaryDeployment != "" {
+			log.Info("Canary cancelled, the application is back to the stable revision", "deploymentName", c.CanaryDeployment)
+			c.Phase, c.Message = "", ""
+		}
+
+		// the first rollout has nothing to compare a canary against
+		if c.StableDeployment == "" {
+			c.StableDeployment = currentName
+		}
+
+		c.CanaryDeployment, c.AbortedDeployment, c.Weight = "", "", 0
+	case c.CanaryDeployment == currentName || runs(c.CanaryDeployment):
+		// the canary carries on, e.g. after a scale
+	case c.AbortedDeployment == currentName || runs(c.AbortedDeployment):
+		// stays aborted until the application changes
+	default:
+		log.Info("Starting canary", "deploymentName", currentName)
+		r.Recorder.Eventf(&app, v12.EventTypeNormal, "CanaryStarted", "Started canary %s", currentName)
+		c.CanaryDeployment, c.AbortedDeployment = currentName, ""
+		c.Step, c.StepStartTime = 0, nil
+		c.Phase, c.Message = feistyv1alpha1.CanaryProgressing, "Waiting for the canary to be ready"
+	}
+
+	replicas := *getReplicas(app)
+	target, targetReplicas := c.StableDeployment, replicas
+	stableReplicas := replicas
+	switch {
+	case c.CanaryDeployment != "":
+		c.Weight = canaryWeight(options, c.Step)
+		target, targetReplicas = c.CanaryDeployment, canaryReplicas(replicas, c.Weight)
+		stableReplicas = stableCanaryReplicas(options, replicas, targetReplicas, c.Weight)
+	case c.AbortedDeployment != "":
+		target, targetReplicas = c.AbortedDeployment, 0
+	}
+
+	deployment, err := r.upsertRevisionDeployment(app, deployments, target, &targetReplicas, *current, ctx)
+	if err != nil {
+		log.Error(err, "Could not upsert deployment", "deploymentName", target)
+		return ctrl.Result{}, err
+	}
+
+	if stable, ok := deployments[c.StableDeployment]; ok && target != c.StableDeployment {
+		if _, err := r.scaleDeployment(stable, stableReplicas, ctx); err != nil {
+			log.Error(err, "Could not scale deployment", "deploymentName", stable.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.deleteDeployments(deployments, []string{c.StableDeployment, c.CanaryDeployment, c.AbortedDeployment}, req, ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if c.CanaryDeployment == "" {
+		return ctrl.Result{}, r.removeCanaryRouting(app, ctx)
+	}
+
+	return r.progressCanary(app, deployment, options, status, req, ctx)
+}
+
+// progressCanary checks the canary and moves it on to the next step when the
+// current one is over
+func (r *ApplicationReconciler) progressCanary(app feistyv1alpha1.Application, canary v1.Deployment, options feistyv1alpha1.CanaryOptions, status *feistyv1alpha1.ApplicationStatus, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+	c := status.Canary
+
+	ready, problem, err := r.checkCanary(app, canary, *options.MaxRestarts, ctx)
+	if err != nil {
+		log.Error(err, "Could not check the canary", "deploymentName", canary.Name)
+		return ctrl.Result{}, err
+	}
+
+	if problem != "" {
+		log.Info("Aborting canary", "deploymentName", canary.Name, "reason", problem)
+		r.Recorder.Eventf(&app, v12.EventTypeWarning, "CanaryAborted", "Aborted canary %s at %d%%: %s", canary.Name, c.Weight, problem)
+
+		c.AbortedDeployment, c.CanaryDeployment = canary.Name, ""
+		c.Phase, c.Message = feistyv1alpha1.CanaryAborted, fmt.Sprintf("Aborted at %d%%: %s", c.Weight, problem)
+		c.Weight, c.StepStartTime = 0, nil
+
+		// the next reconcile scales the canary down and the stable Deployment back up
+		return ctrl.Result{Requeue: true}, r.removeCanaryRouting(app, ctx)
+	}
+
+	// in maintenance all the ingress traffic goes to the maintenance page
+	if offline(app) {
+		if err := r.removeCanaryRouting(app, ctx); err != nil {
+			log.Error(err, "Could not remove canary routing")
+			return ctrl.Result{}, err
+		}
+	} else if options.TrafficRouting == feistyv1alpha1.CanaryNginxRouting && app.Spec.RoutingEnabled && app.Spec.Port != 0 {
+		if err := r.upsertCanaryRouting(app, canary.Name, c.Weight, ctx); err != nil {
+			log.Error(err, "Could not upsert canary routing")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !ready {
+		c.Message = fmt.Sprintf("Waiting for the canary to be ready at %d%%", c.Weight)
+		return ctrl.Result{RequeueAfter: canaryCheckInterval}, nil
+	}
+
+	step := c.Step
+	result, promoted := nextCanaryStep(c, options, canary.Name, app.Annotations[constants.CanaryResumeAnnotation], time.Now())
+	switch {
+	case promoted:
+		log.Info("Promoting canary", "deploymentName", canary.Name)
+		r.Recorder.Eventf(&app, v12.EventTypeNormal, "CanaryPromoted", "Promoted canary %s", canary.Name)
+
+		// the next reconcile deletes the previous stable Deployment
+		return result, r.removeCanaryRouting(app, ctx)
+	case c.Step != step:
+		log.Info("Canary step done", "deploymentName", canary.Name, "step", c.Step)
+	}
+
+	return result, nil
+}
+
+// nextCanaryStep is the step machine of a ready canary: it waits for the
+// current step's duration or to be resumed from a pause, then moves on to the
+// next step and promotes the canary after the last one. It returns when to
+// check again and whether the canary was promoted.
+func nextCanaryStep(c *feistyv1alpha1.CanaryStatus, options feistyv1alpha1.CanaryOptions, canary string, resumeKey string, now time.Time) (ctrl.Result, bool) {
+	steps := len(options.Steps)
+	if c.StepStartTime == nil {
+		start := metav1.NewTime(now)
+		c.StepStartTime = &start
+	}
+
+	if c.Step >= steps {
+		c.StableDeployment, c.CanaryDeployment = canary, ""
+		c.Phase, c.Message = feistyv1alpha1.CanaryPromoted, fmt.Sprintf("Promoted %s", canary)
+		c.StepStartTime = nil
+
+		return ctrl.Result{Requeue: true}, true
+	}
+
+	step := options.Steps[c.Step]
+	switch {
+	case step.Pause && resumeKey != c.ResumeKey():
+		c.Phase = feistyv1alpha1.CanaryPaused
+		c.Message = fmt.Sprintf("Paused at step %d of %d (%d%%), resume with feisty releases:resume", c.Step+1, steps, c.Weight)
+		return ctrl.Result{RequeueAfter: canaryCheckInterval}, false
+	case !step.Pause && step.Duration != nil:
+		if remaining := c.StepStartTime.Add(step.Duration.Duration).Sub(now); remaining > 0 {
+			c.Phase = feistyv1alpha1.CanaryProgressing
+			c.Message = fmt.Sprintf("At step %d of %d (%d%%) for another %s", c.Step+1, steps, c.Weight, duration.HumanDuration(remaining))
+			if remaining > canaryCheckInterval {
+				remaining = canaryCheckInterval
+			}
+
+			return ctrl.Result{RequeueAfter: remaining}, false
+		}
+	}
+
+	c.Step++
+	c.StepStartTime = nil
+	c.Phase = feistyv1alpha1.CanaryProgressing
+	c.Message = fmt.Sprintf("Moving on to %d%%", canaryWeight(options, c.Step))
+
+	// the next reconcile scales the Deployments to the weight of the next step
+	return ctrl.Result{Requeue: true}, false
+}
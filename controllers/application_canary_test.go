@@ -0,0 +1,166 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCanaryReplicas(t *testing.T) {
+	tests := []struct {
+		replicas int32
+		weight   int
+		want     int32
+	}{
+		{replicas: 0, weight: 10, want: 0},
+		{replicas: 1, weight: 10, want: 1},
+		{replicas: 10, weight: 10, want: 1},
+		{replicas: 10, weight: 15, want: 2},
+		{replicas: 4, weight: 50, want: 2},
+		{replicas: 3, weight: 100, want: 3},
+		{replicas: 3, weight: 150, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := canaryReplicas(tt.replicas, tt.weight); got != tt.want {
+			t.Errorf("canaryReplicas(%d, %d) = %d, want %d", tt.replicas, tt.weight, got, tt.want)
+		}
+	}
+}
+
+func TestStableCanaryReplicas(t *testing.T) {
+	replicaRouting := feistyv1alpha1.CanaryOptions{TrafficRouting: feistyv1alpha1.CanaryReplicaRouting}
+	nginxRouting := feistyv1alpha1.CanaryOptions{TrafficRouting: feistyv1alpha1.CanaryNginxRouting}
+
+	tests := []struct {
+		name     string
+		options  feistyv1alpha1.CanaryOptions
+		replicas int32
+		canary   int32
+		weight   int
+		want     int32
+	}{
+		{name: "replicas split by weight", options: replicaRouting, replicas: 10, canary: 1, weight: 10, want: 9},
+		{name: "a single replica keeps the stable pod", options: replicaRouting, replicas: 1, canary: 1, weight: 10, want: 1},
+		{name: "all the traffic goes to the canary", options: replicaRouting, replicas: 1, canary: 1, weight: 100, want: 0},
+		{name: "stopped", options: replicaRouting, replicas: 0, canary: 0, weight: 10, want: 0},
+		{name: "nginx keeps every stable replica", options: nginxRouting, replicas: 4, canary: 1, weight: 10, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stableCanaryReplicas(tt.options, tt.replicas, tt.canary, tt.weight); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanaryWeight(t *testing.T) {
+	options := canaryOptions(feistyv1alpha1.Application{})
+	for step, want := range []int{10, 50, 100, 100} {
+		if got := canaryWeight(options, step); got != want {
+			t.Errorf("canaryWeight(%d) = %d, want %d", step, got, want)
+		}
+	}
+}
+
+func TestNextCanaryStep(t *testing.T) {
+	now := time.Now()
+	started := metav1.NewTime(now.Add(-2 * time.Minute))
+	justStarted := metav1.NewTime(now.Add(-10 * time.Second))
+	options := feistyv1alpha1.CanaryOptions{Steps: []feistyv1alpha1.CanaryStep{
+		{Weight: 10, Duration: &metav1.Duration{Duration: time.Minute}},
+		{Weight: 50, Pause: true},
+	}}
+
+	tests := []struct {
+		name         string
+		status       feistyv1alpha1.CanaryStatus
+		resumeKey    string
+		wantStep     int
+		wantPhase    feistyv1alpha1.CanaryPhase
+		wantPromoted bool
+		wantRequeue  bool
+	}{
+		{
+			name:      "the step starts once the canary is ready",
+			status:    feistyv1alpha1.CanaryStatus{CanaryDeployment: "app-v2", StableDeployment: "app-v1"},
+			wantStep:  0,
+			wantPhase: feistyv1alpha1.CanaryProgressing,
+		},
+		{
+			name:      "the step lasts its duration",
+			status:    feistyv1alpha1.CanaryStatus{CanaryDeployment: "app-v2", StableDeployment: "app-v1", StepStartTime: &justStarted},
+			wantStep:  0,
+			wantPhase: feistyv1alpha1.CanaryProgressing,
+		},
+		{
+			name:        "the next step follows",
+			status:      feistyv1alpha1.CanaryStatus{CanaryDeployment: "app-v2", StableDeployment: "app-v1", StepStartTime: &started},
+			wantStep:    1,
+			wantPhase:   feistyv1alpha1.CanaryProgressing,
+			wantRequeue: true,
+		},
+		{
+			name:      "a pause waits to be resumed",
+			status:    feistyv1alpha1.CanaryStatus{CanaryDeployment: "app-v2", StableDeployment: "app-v1", Step: 1, StepStartTime: &started},
+			resumeKey: "app-v2/0",
+			wantStep:  1,
+			wantPhase: feistyv1alpha1.CanaryPaused,
+		},
+		{
+			name:        "a resumed pause moves on",
+			status:      feistyv1alpha1.CanaryStatus{CanaryDeployment: "app-v2", StableDeployment: "app-v1", Step: 1, StepStartTime: &started},
+			resumeKey:   "app-v2/1",
+			wantStep:    2,
+			wantPhase:   feistyv1alpha1.CanaryProgressing,
+			wantRequeue: true,
+		},
+		{
+			name:         "the canary is promoted after the last step",
+			status:       feistyv1alpha1.CanaryStatus{CanaryDeployment: "app-v2", StableDeployment: "app-v1", Step: 2, StepStartTime: &started},
+			wantStep:     2,
+			wantPhase:    feistyv1alpha1.CanaryPromoted,
+			wantPromoted: true,
+			wantRequeue:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.status
+			result, promoted := nextCanaryStep(&c, options, "app-v2", tt.resumeKey, now)
+			if c.Step != tt.wantStep || c.Phase != tt.wantPhase || promoted != tt.wantPromoted || result.Requeue != tt.wantRequeue {
+				t.Errorf("got step %d, phase %s, promoted %t, requeue %t, want step %d, phase %s, promoted %t, requeue %t",
+					c.Step, c.Phase, promoted, result.Requeue, tt.wantStep, tt.wantPhase, tt.wantPromoted, tt.wantRequeue)
+			}
+
+			if !tt.wantRequeue && result.RequeueAfter <= 0 {
+				t.Errorf("got no requeue")
+			}
+
+			if promoted && (c.StableDeployment != "app-v2" || c.CanaryDeployment != "") {
+				t.Errorf("got stable %s and canary %s after promotion", c.StableDeployment, c.CanaryDeployment)
+			}
+		})
+	}
+}
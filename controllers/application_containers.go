@@ -0,0 +1,194 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	v12 "k8s.io/api/core/v1"
+)
+
+// validateContainers makes sure every container of the pods gets a name of
+// its own, the application's container being named after it
+func validateContainers(app feistyv1alpha1.Application) error {
+	names := map[string]bool{app.Name: true}
+	for _, extra := range append(append([]feistyv1alpha1.ExtraContainer{}, app.Spec.Sidecars...), app.Spec.InitContainers...) {
+		if extra.Image == "" {
+			return fmt.Errorf("container %s has no image", extra.Name)
+		}
+
+		if strings.ContainsAny(extra.Image, " \t\n") {
+			return fmt.Errorf("container %s has an invalid image %q", extra.Name, extra.Image)
+		}
+
+		if names[extra.Name] {
+			return fmt.Errorf("container name %s is used more than once, the application's container is named %s", extra.Name, app.Name)
+		}
+
+		names[extra.Name] = true
+	}
+
+	return nil
+}
+
+func containersCondition(err error) feistyv1alpha1.ApplicationCondition {
+	if err != nil {
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationContainersValid,
+			Status:  v12.ConditionFalse,
+			Reason:  "InvalidContainers",
+			Message: err.Error(),
+		}
+	}
+
+	return feistyv1alpha1.ApplicationCondition{
+		Type:   feistyv1alpha1.ApplicationContainersValid,
+		Status: v12.ConditionTrue,
+		Reason: "ContainersValid",
+	}
+}
+
+func configEnvFrom(app feistyv1alpha1.Application) []v12.EnvFromSource {
+	return []v12.EnvFromSource{{
+		SecretRef: &v12.SecretEnvSource{
+			LocalObjectReference: v12.LocalObjectReference{
+				Name: app.Spec.AppConfigRef,
+			},
+		},
+	}}
+}
+
+// applyAppContainer sets the parts of the application's container that come
+// from the application
+func applyAppContainer(app feistyv1alpha1.Application, container *v12.Container) {
+	container.Image = app.Spec.Image
+
+	if app.Spec.Port != 0 {
+		container.Ports = []v12.ContainerPort{{
+			Name:          "http",
+			ContainerPort: int32(app.Spec.Port),
+		}}
+	}
+
+	if app.Spec.AppConfigRef != "" {
+		container.EnvFrom = configEnvFrom(app)
+	}
+
+	container.VolumeMounts = appVolumeMounts(app)
+}
+
+// applyExtraContainer sets a sidecar or init container from its declaration
+func applyExtraContainer(app feistyv1alpha1.Application, extra feistyv1alpha1.ExtraContainer, container *v12.Container) {
+	container.Image = extra.Image
+	container.Command = extra.Command
+	container.Args = extra.Args
+	container.Env = extra.Env
+	container.Ports = extra.Ports
+	container.VolumeMounts = extra.VolumeMounts
+	container.EnvFrom = nil
+	if extra.InheritConfig && app.Spec.AppConfigRef != "" {
+		container.EnvFrom = configEnvFrom(app)
+	}
+}
+
+// mergeContainers lays out the containers of a pod template: the ones the
+// application declares, in order and keeping whatever else was set on them
+// (e.g. API server defaults), followed by the ones someone else injected.
+// Containers the operator added before that aren't declared anymore are
+// dropped, those are listed by the annotation.
+func mergeContainers(template *v12.PodTemplateSpec, existing []v12.Container, declared []v12.Container, apply func(*v12.Container), annotation string, managed []string) []v12.Container {
+	previous := map[string]bool{}
+	if template.ObjectMeta.Annotations[annotation] != "" {
+		for _, name := range strings.Split(template.ObjectMeta.Annotations[annotation], ",") {
+			previous[name] = true
+		}
+	}
+
+	byName := map[string]v12.Container{}
+	for _, container := range existing {
+		byName[container.Name] = container
+	}
+
+	isDeclared := map[string]bool{}
+	var containers []v12.Container
+	for _, container := range declared {
+		if current, ok := byName[container.Name]; ok {
+			container = current
+		}
+
+		apply(&container)
+		isDeclared[container.Name] = true
+		containers = append(containers, container)
+	}
+
+	for _, container := range existing {
+		if !isDeclared[container.Name] && !previous[container.Name] {
+			containers = append(containers, container)
+		}
+	}
+
+	if len(managed) > 0 {
+		if template.ObjectMeta.Annotations == nil {
+			template.ObjectMeta.Annotations = map[string]string{}
+		}
+
+		template.ObjectMeta.Annotations[annotation] = strings.Join(managed, ",")
+	} else {
+		delete(template.ObjectMeta.Annotations, annotation)
+	}
+
+	return containers
+}
+
+// applyContainers sets the application's container, sidecars and init
+// containers on a pod template. The application's container is found by name
+// rather than position so sidecars, declared or injected, can't take its place.
+func applyContainers(app feistyv1alpha1.Application, template *v12.PodTemplateSpec) {
+	extras := map[string]feistyv1alpha1.ExtraContainer{}
+	for _, extra := range append(append([]feistyv1alpha1.ExtraContainer{}, app.Spec.Sidecars...), app.Spec.InitContainers...) {
+		extras[extra.Name] = extra
+	}
+
+	apply := func(container *v12.Container) {
+		if container.Name == app.Name {
+			applyAppContainer(app, container)
+			return
+		}
+
+		applyExtraContainer(app, extras[container.Name], container)
+	}
+
+	containers := []v12.Container{{Name: app.Name}}
+	var sidecars []string
+	for _, sidecar := range app.Spec.Sidecars {
+		containers = append(containers, v12.Container{Name: sidecar.Name})
+		sidecars = append(sidecars, sidecar.Name)
+	}
+
+	var initContainers []v12.Container
+	var inits []string
+	for _, init := range app.Spec.InitContainers {
+		initContainers = append(initContainers, v12.Container{Name: init.Name})
+		inits = append(inits, init.Name)
+	}
+
+	template.Spec.Containers = mergeContainers(template, template.Spec.Containers, containers, apply, constants.ContainersAnnotation, sidecars)
+	template.Spec.InitContainers = mergeContainers(template, template.Spec.InitContainers, initContainers, apply, constants.InitContainersAnnotation, inits)
+}
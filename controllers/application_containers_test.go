@@ -0,0 +1,111 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrferos/feisty/constants"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeContainers(t *testing.T) {
+	apply := func(container *v12.Container) {
+		container.Image = container.Name + ":v2"
+	}
+
+	declared := func(names ...string) []v12.Container {
+		var containers []v12.Container
+		for _, name := range names {
+			containers = append(containers, v12.Container{Name: name})
+		}
+
+		return containers
+	}
+
+	tests := []struct {
+		name           string
+		annotation     string
+		existing       []v12.Container
+		declared       []v12.Container
+		managed        []string
+		want           []v12.Container
+		wantAnnotation string
+	}{
+		{
+			name:           "new pod template",
+			declared:       declared("app", "proxy"),
+			managed:        []string{"proxy"},
+			want:           []v12.Container{{Name: "app", Image: "app:v2"}, {Name: "proxy", Image: "proxy:v2"}},
+			wantAnnotation: "proxy",
+		},
+		{
+			name:       "defaults of declared containers are kept",
+			annotation: "proxy",
+			existing: []v12.Container{
+				{Name: "app", Image: "app:v1", TerminationMessagePath: "/dev/termination-log"},
+				{Name: "proxy", Image: "proxy:v1"},
+			},
+			declared:       declared("app", "proxy"),
+			managed:        []string{"proxy"},
+			want:           []v12.Container{{Name: "app", Image: "app:v2", TerminationMessagePath: "/dev/termination-log"}, {Name: "proxy", Image: "proxy:v2"}},
+			wantAnnotation: "proxy",
+		},
+		{
+			name:     "injected containers are kept after the declared ones",
+			existing: []v12.Container{{Name: "istio-proxy", Image: "istio"}, {Name: "app", Image: "app:v1"}},
+			declared: declared("app"),
+			want:     []v12.Container{{Name: "app", Image: "app:v2"}, {Name: "istio-proxy", Image: "istio"}},
+		},
+		{
+			name:           "containers that aren't declared anymore are dropped",
+			annotation:     "proxy,shipper",
+			existing:       []v12.Container{{Name: "app"}, {Name: "proxy"}, {Name: "shipper"}},
+			declared:       declared("app", "shipper"),
+			managed:        []string{"shipper"},
+			want:           []v12.Container{{Name: "app", Image: "app:v2"}, {Name: "shipper", Image: "shipper:v2"}},
+			wantAnnotation: "shipper",
+		},
+		{
+			name:       "the annotation goes with the last container",
+			annotation: "proxy",
+			existing:   []v12.Container{{Name: "app"}, {Name: "proxy"}},
+			declared:   declared("app"),
+			want:       []v12.Container{{Name: "app", Image: "app:v2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &v12.PodTemplateSpec{}
+			if tt.annotation != "" {
+				template.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{constants.ContainersAnnotation: tt.annotation}}
+			}
+
+			got := mergeContainers(template, tt.existing, tt.declared, apply, constants.ContainersAnnotation, tt.managed)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+
+			if annotation := template.ObjectMeta.Annotations[constants.ContainersAnnotation]; annotation != tt.wantAnnotation {
+				t.Errorf("got annotation %q, want %q", annotation, tt.wantAnnotation)
+			}
+		})
+	}
+}
@@ -24,12 +24,17 @@ import (
 	v1 "k8s.io/api/apps/v1"
 	v12 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strconv"
 
 	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
 )
@@ -42,28 +47,137 @@ var (
 // ApplicationReconciler reconciles a Application object
 type ApplicationReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log       logr.Logger
+	Scheme    *runtime.Scheme
+	Retention revisions.RetentionPolicy
+	Recorder  record.EventRecorder
+	// MaintenanceImage serves the maintenance page, DefaultMaintenanceImage
+	// when it's empty
+	MaintenanceImage string
+	// WakeProxyService is the DNS name of the wake-up proxy's Service,
+	// applications can't sleep when idle without it
+	WakeProxyService string
 }
 
 // +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=applications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=applicationrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=applicationrevisions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 
 func getAppLabels(app feistyv1alpha1.Application) map[string]string {
 	return map[string]string{
-		"app": app.Name,
+		constants.AppLabel: app.Name,
 	}
 }
 
-func (r *ApplicationReconciler) upsertDeployment(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
-	log := r.Log.WithValues("application", req.NamespacedName)
-	appLabels := getAppLabels(app)
+// getPodLabels returns the labels for the pod template, which is a superset of
+// the app labels since those are used as the (immutable) deployment selector
+func getPodLabels(app feistyv1alpha1.Application) map[string]string {
+	podLabels := getAppLabels(app)
+	podLabels[constants.ProcessTypeLabel] = constants.DefaultProcessType
+
+	return podLabels
+}
+
+// podTemplateChanged compares two pod templates, ignoring the revision labels
+func podTemplateChanged(before v12.PodTemplateSpec, after v12.PodTemplateSpec) bool {
+	for _, template := range []*v12.PodTemplateSpec{&before, &after} {
+		labels := map[string]string{}
+		for k, v := range template.Labels {
+			if k != constants.RevisionLabel && k != constants.RevisionHashLabel {
+				labels[k] = v
+			}
+		}
+
+		template.Labels = labels
+	}
+
+	return !apiequality.Semantic.DeepEqual(before, after)
+}
+
+// applyPodTemplate sets the parts of a pod template that come from the
+// application, leaving anything else (e.g. API server defaults) alone
+func applyPodTemplate(app feistyv1alpha1.Application, template *v12.PodTemplateSpec) {
+	if template.ObjectMeta.Labels == nil {
+		template.ObjectMeta.Labels = map[string]string{}
+	}
+
+	for k, v := range getPodLabels(app) {
+		template.ObjectMeta.Labels[k] = v
+	}
 
+	if app.Spec.RestartTime != "" {
+		if template.ObjectMeta.Annotations == nil {
+			template.ObjectMeta.Annotations = map[string]string{}
+		}
+
+		template.ObjectMeta.Annotations[restartDeploymentAnnotationKey] = app.Spec.RestartTime
+	}
+
+	applyVolumes(app, template)
+	applyContainers(app, template)
+	applyScheduling(app, template)
+}
+
+// labelRevision labels a pod template with the revision its pods are created from
+func labelRevision(template *v12.PodTemplateSpec, current revisions.NumberedRevision) error {
+	hash, err := revisions.ContentHash(current.Revision)
+	if err != nil {
+		return err
+	}
+
+	template.ObjectMeta.Labels[constants.RevisionLabel] = "v" + strconv.Itoa(current.Number)
+	template.ObjectMeta.Labels[constants.RevisionHashLabel] = hash
+
+	return nil
+}
+
+func getReplicas(app feistyv1alpha1.Application) *int32 {
 	replicas := int32(0)
-	if app.Spec.Replicas > 0 {
+	if app.Spec.Replicas > 0 && !asleep(app) {
 		replicas = int32(app.Spec.Replicas)
 	}
 
+	return &replicas
+}
+
+// applyDeploymentSpec sets the rollout settings of a Deployment, unset ones
+// are left to the API server defaults
+func applyDeploymentSpec(app feistyv1alpha1.Application, spec *v1.DeploymentSpec) {
+	spec.Strategy = v1.DeploymentStrategy{
+		Type: v1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &v1.RollingUpdateDeployment{
+			MaxSurge:       app.Spec.MaxSurge,
+			MaxUnavailable: app.Spec.MaxUnavailable,
+		},
+	}
+
+	// a new pod would wait forever for a volume the old pod holds on to
+	if attachesToOneNode(app) {
+		spec.Strategy = v1.DeploymentStrategy{Type: v1.RecreateDeploymentStrategyType}
+	}
+
+	spec.MinReadySeconds = int32(app.Spec.MinReadySeconds)
+	spec.ProgressDeadlineSeconds = nil
+	if app.Spec.ProgressDeadlineSeconds != nil {
+		deadline := int32(*app.Spec.ProgressDeadlineSeconds)
+		spec.ProgressDeadlineSeconds = &deadline
+	}
+}
+
+func (r *ApplicationReconciler) upsertDeployment(app feistyv1alpha1.Application, current *revisions.NumberedRevision, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+	appLabels := getAppLabels(app)
+
 	doCreate := false
 	var deployment v1.Deployment
 	if err := r.Get(ctx, req.NamespacedName, &deployment); err != nil {
@@ -79,56 +193,95 @@ func (r *ApplicationReconciler) upsertDeployment(app feistyv1alpha1.Application,
 				},
 				Template: v12.PodTemplateSpec{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:   app.Name,
-						Labels: appLabels,
-					},
-					Spec: v12.PodSpec{
-						Containers: []v12.Container{{
-							Name: app.Name,
-						}},
+						Name: app.Name,
 					},
 				},
 			},
 		}
 	}
 
-	if app.Spec.RestartTime != "" {
-		if deployment.Spec.Template.ObjectMeta.Annotations == nil {
-			deployment.Spec.Template.ObjectMeta.Annotations = map[string]string{}
+	before := deployment.Spec.Template.DeepCopy()
+	applyPodTemplate(app, &deployment.Spec.Template)
+	applyDeploymentSpec(app, &deployment.Spec)
+	deployment.Spec.Replicas = getReplicas(app)
+
+	// pods are only labelled with a newer revision when they're replaced, changes
+	// like scaling make a revision without replacing any pods
+	if current != nil && (doCreate || podTemplateChanged(*before, deployment.Spec.Template)) {
+		if err := labelRevision(&deployment.Spec.Template, *current); err != nil {
+			log.Error(err, "Could not hash the revision", "revisionName", current.Revision.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if doCreate {
+		_ = ctrl.SetControllerReference(&app, &deployment, r.Scheme)
+		if err := r.Create(ctx, &deployment); err != nil {
+			log.Error(err, "Could not create deployment")
+			return ctrl.Result{}, err
+		}
+	} else {
+		if err := r.Update(ctx, &deployment); err != nil {
+			log.Error(err, "Could not update deployment")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// upsertPodDisruptionBudget keeps all but one of the selected pods available
+// during voluntary disruptions like node drains. A single replica can't be kept
+// available so there's no budget for it, it would only block drains.
+func (r *ApplicationReconciler) upsertPodDisruptionBudget(app feistyv1alpha1.Application, selector map[string]string, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	doCreate := false
+	var pdb policyv1beta1.PodDisruptionBudget
+	if err := r.Get(ctx, req.NamespacedName, &pdb); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch pdb")
+			return ctrl.Result{}, err
 		}
 
-		deployment.Spec.Template.ObjectMeta.Annotations[restartDeploymentAnnotationKey] = app.Spec.RestartTime
+		doCreate = true
+		pdb = policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      app.Name,
+				Namespace: app.Namespace,
+			},
+		}
 	}
 
-	deployment.Spec.Replicas = &replicas
-	deployment.Spec.Template.Spec.Containers[0].Image = app.Spec.Image
+	replicas := int(*getReplicas(app))
+	if replicas <= 1 {
+		if !doCreate {
+			if err := r.Delete(ctx, &pdb); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Could not delete pdb")
+				return ctrl.Result{}, err
+			}
+		}
 
-	if app.Spec.Port != 0 {
-		deployment.Spec.Template.Spec.Containers[0].Ports = []v12.ContainerPort{{
-			Name:          "http",
-			ContainerPort: int32(app.Spec.Port),
-		}}
+		return ctrl.Result{}, nil
 	}
 
-	if app.Spec.AppConfigRef != "" {
-		deployment.Spec.Template.Spec.Containers[0].EnvFrom = []v12.EnvFromSource{{
-			SecretRef: &v12.SecretEnvSource{
-				LocalObjectReference: v12.LocalObjectReference{
-					Name: app.Spec.AppConfigRef,
-				},
-			},
-		}}
+	// the serving Deployment runs fewer pods than the replicas next to a canary
+	maxUnavailable := intstr.FromInt(1)
+	pdb.Spec.MinAvailable = nil
+	pdb.Spec.MaxUnavailable = &maxUnavailable
+	pdb.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: selector,
 	}
 
 	if doCreate {
-		_ = ctrl.SetControllerReference(&app, &deployment, r.Scheme)
-		if err := r.Create(ctx, &deployment); err != nil {
-			log.Error(err, "Could not create deployment")
+		_ = ctrl.SetControllerReference(&app, &pdb, r.Scheme)
+		if err := r.Create(ctx, &pdb); err != nil {
+			log.Error(err, "Could not create pdb")
 			return ctrl.Result{}, err
 		}
 	} else {
-		if err := r.Update(ctx, &deployment); err != nil {
-			log.Error(err, "Could not update deployment")
+		if err := r.Update(ctx, &pdb); err != nil {
+			log.Error(err, "Could not update pdb")
 			return ctrl.Result{}, err
 		}
 	}
@@ -136,9 +289,8 @@ func (r *ApplicationReconciler) upsertDeployment(app feistyv1alpha1.Application,
 	return ctrl.Result{}, nil
 }
 
-func (r *ApplicationReconciler) upsertService(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+func (r *ApplicationReconciler) upsertService(app feistyv1alpha1.Application, selector map[string]string, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
 	log := r.Log.WithValues("application", req.NamespacedName)
-	appLabels := getAppLabels(app)
 
 	doCreate := false
 	var svc v12.Service
@@ -158,12 +310,13 @@ func (r *ApplicationReconciler) upsertService(app feistyv1alpha1.Application, re
 						IntVal: int32(app.Spec.Port),
 					},
 				}},
-				Selector: appLabels,
-				Type:     "ClusterIP",
+				Type: "ClusterIP",
 			},
 		}
 	}
 
+	svc.Spec.Selector = selector
+
 	if doCreate {
 		_ = ctrl.SetControllerReference(&app, &svc, r.Scheme)
 		if err := r.Create(ctx, &svc); err != nil {
@@ -180,21 +333,8 @@ func (r *ApplicationReconciler) upsertService(app feistyv1alpha1.Application, re
 	return ctrl.Result{}, nil
 }
 
-func (r *ApplicationReconciler) upsertIngress(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
-	log := r.Log.WithValues("application", req.NamespacedName)
-
-	doCreate := false
-	var ingress v1beta1.Ingress
-	if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
-		doCreate = true
-		ingress = v1beta1.Ingress{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      app.Name,
-				Namespace: app.Namespace,
-			},
-		}
-	}
-
+// ingressRules routes the application's domains to a Service
+func ingressRules(app feistyv1alpha1.Application, serviceName string) []v1beta1.IngressRule {
 	// TODO: add code here to deal with default domain
 	var rules []v1beta1.IngressRule
 	domains := app.Spec.Domains
@@ -206,7 +346,7 @@ func (r *ApplicationReconciler) upsertIngress(app feistyv1alpha1.Application, re
 					Paths: []v1beta1.HTTPIngressPath{{
 						Path: "/",
 						Backend: v1beta1.IngressBackend{
-							ServiceName: app.Name,
+							ServiceName: serviceName,
 							ServicePort: intstr.IntOrString{
 								IntVal: defaultExposedPort,
 							},
@@ -217,7 +357,32 @@ func (r *ApplicationReconciler) upsertIngress(app feistyv1alpha1.Application, re
 		})
 	}
 
-	ingress.Spec.Rules = rules
+	return rules
+}
+
+func (r *ApplicationReconciler) upsertIngress(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	doCreate := false
+	var ingress v1beta1.Ingress
+	if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
+		doCreate = true
+		ingress = v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      app.Name,
+				Namespace: app.Namespace,
+			},
+		}
+	}
+
+	serviceName := app.Name
+	if offline(app) {
+		serviceName = maintenanceName(app)
+	} else if r.sleeps(app) {
+		serviceName = wakeName(app)
+	}
+
+	ingress.Spec.Rules = ingressRules(app, serviceName)
 
 	if doCreate {
 		_ = ctrl.SetControllerReference(&app, &ingress, r.Scheme)
@@ -235,6 +400,79 @@ func (r *ApplicationReconciler) upsertIngress(app feistyv1alpha1.Application, re
 	return ctrl.Result{}, nil
 }
 
+func (r *ApplicationReconciler) updateStatus(app feistyv1alpha1.Application, status feistyv1alpha1.ApplicationStatus, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	status.ObservedGeneration = app.Generation
+	if apiequality.Semantic.DeepEqual(app.Status, status) {
+		return nil
+	}
+
+	patch := client.MergeFrom(app.DeepCopy())
+	app.Status = status
+	if err := r.Status().Patch(ctx, &app, patch); err != nil {
+		log.Error(err, "Could not update application status")
+		return err
+	}
+
+	return nil
+}
+
+// setCondition adds or replaces the condition of the same type, keeping the
+// transition time when its status didn't change
+func setCondition(status *feistyv1alpha1.ApplicationStatus, condition feistyv1alpha1.ApplicationCondition) {
+	for i, existing := range status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+
+		condition.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != condition.Status {
+			condition.LastTransitionTime = metav1.Now()
+		}
+
+		status.Conditions[i] = condition
+		return
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	status.Conditions = append(status.Conditions, condition)
+}
+
+func revisionCondition(err error) feistyv1alpha1.ApplicationCondition {
+	if err != nil {
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationRevisionRecorded,
+			Status:  v12.ConditionFalse,
+			Reason:  "RevisionFailed",
+			Message: err.Error(),
+		}
+	}
+
+	return feistyv1alpha1.ApplicationCondition{
+		Type:   feistyv1alpha1.ApplicationRevisionRecorded,
+		Status: v12.ConditionTrue,
+		Reason: "RevisionRecorded",
+	}
+}
+
+func pruneCondition(err error) feistyv1alpha1.ApplicationCondition {
+	if err != nil {
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationRevisionsPruned,
+			Status:  v12.ConditionFalse,
+			Reason:  "PruneFailed",
+			Message: err.Error(),
+		}
+	}
+
+	return feistyv1alpha1.ApplicationCondition{
+		Type:   feistyv1alpha1.ApplicationRevisionsPruned,
+		Status: v12.ConditionTrue,
+		Reason: "RevisionsPruned",
+	}
+}
+
 func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("application", req.NamespacedName)
@@ -249,22 +487,132 @@ func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	deploymentExist := false
+	// the pods are scheduled with the namespace defaults, this copy of the
+	// application is never written back
+	defaults, err := r.schedulingDefaults(app.Namespace, ctx)
+	if err != nil {
+		log.Error(err, "Unable to read scheduling defaults")
+		return ctrl.Result{}, err
+	}
+
+	app.Spec.Scheduling = mergeScheduling(defaults, app.Spec.Scheduling)
+
 	if app.Spec.Image == "" {
 		log.Info("No deployment action taken because no image was supplied")
-		return ctrl.Result{}, nil
-	} else {
-		if res, err := r.upsertDeployment(app, req, ctx); err != nil {
+		return r.reconcileWithoutImage(app, req, ctx)
+	}
+
+	// the Deployments keep running the last valid containers and volumes,
+	// everything else still follows the application
+	containersErr := validateContainers(app)
+	if containersErr != nil {
+		log.Info("Pods not updated because the containers are invalid", "reason", containersErr.Error())
+		r.Recorder.Event(&app, v12.EventTypeWarning, "InvalidContainers", containersErr.Error())
+	}
+
+	volumesErr := validateVolumes(app)
+	if volumesErr != nil {
+		log.Info("Pods not updated because the volumes are invalid", "reason", volumesErr.Error())
+		r.Recorder.Event(&app, v12.EventTypeWarning, "InvalidVolumes", volumesErr.Error())
+	} else if err := r.upsertVolumeClaims(app, req, ctx); err != nil {
+		log.Error(err, "There was an error doing pvc handling")
+		status := app.Status.DeepCopy()
+		setCondition(status, volumesCondition(app, err))
+		_ = r.updateStatus(app, *status, req, ctx)
+
+		return ctrl.Result{}, err
+	}
+
+	valid := containersErr == nil && volumesErr == nil
+
+	// the revision is made first so the pods it rolls out can be labelled with
+	// it, there's none for pods that can't be rolled out
+	var current *revisions.NumberedRevision
+	var revErr, digestErr error
+	deployed := app
+	if valid {
+		current, revErr = rev.CreateIfNeeded(req.NamespacedName, ctx)
+		if revErr != nil {
+			log.Error(revErr, "There was an error creating the revision")
+		}
+
+		// the image is deployed by digest once a pod has resolved it, so pods made
+		// later on, e.g. when scaling, run what the revision ran even when the tag
+		// has moved. Pinning the image replaces the pods once more, with the same
+		// image. The request is retried when the digest couldn't be recorded, the
+		// pods keep the digest the revision already had meanwhile.
+		var digest string
+		if digest, digestErr = rev.RecordImageDigest(req.NamespacedName, ctx); digestErr != nil {
+			log.Error(digestErr, "There was an error recording the image digest")
+			if current != nil {
+				digest = current.Revision.Spec.ImageDigest
+			}
+		}
+
+		if digest != "" && current != nil {
+			pinned := current.Revision.Spec
+			pinned.ImageDigest = digest
+			deployed.Spec.Image = revisions.PinnedImage(pinned)
+		}
+	}
+
+	status := app.Status.DeepCopy()
+	sleepResult := r.updateSleep(app, status)
+	result := ctrl.Result{}
+	deploymentExist := false
+	switch {
+	case !valid:
+		// rejected volumes may be the ones that can't be shared by the
+		// requested replicas, so the replicas aren't raised until they're fixed
+		exists, err := r.scaleServingDeployment(app, *status, volumesErr != nil, ctx)
+		if err != nil {
+			log.Error(err, "There was an error scaling the deployment")
+			return ctrl.Result{}, err
+		}
+
+		deploymentExist = exists
+	case strategyOf(app) == feistyv1alpha1.BlueGreenStrategy:
+		res, err := r.upsertBlueGreen(deployed, current, status, req, ctx)
+		if err != nil {
+			log.Error(err, "There was an error doing blue/green deployment handling")
+			return res, err
+		}
+
+		result = res
+		deploymentExist = status.BlueGreen != nil && status.BlueGreen.ActiveDeployment != ""
+	case strategyOf(app) == feistyv1alpha1.CanaryStrategy:
+		res, err := r.upsertCanary(deployed, current, status, req, ctx)
+		if err != nil {
+			log.Error(err, "There was an error doing canary deployment handling")
+			return res, err
+		}
+
+		result = res
+		deploymentExist = status.Canary != nil && status.Canary.StableDeployment != ""
+	default:
+		if res, err := r.upsertDeployment(deployed, current, req, ctx); err != nil {
 			log.Error(err, "There was an error doing deployment handling")
 			return res, err
 		} else {
 			deploymentExist = true
 		}
+
+		if err := r.cleanupDeployments(deployed, status, req, ctx); err != nil {
+			log.Error(err, "There was an error cleaning up deployments")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if deploymentExist {
+		if res, err := r.upsertPodDisruptionBudget(app, servingSelector(app, *status), req, ctx); err != nil {
+			log.Error(err, "There was an error doing pdb handling")
+			return res, err
+		}
 	}
 
 	svcExists := false
 	if app.Spec.Port != 0 && deploymentExist {
-		if res, err := r.upsertService(app, req, ctx); err != nil {
+		if res, err := r.upsertService(app, serviceSelector(app, *status), req, ctx); err != nil {
 			log.Error(err, "There was an error doing service handling")
 			return res, err
 		} else {
@@ -272,24 +620,100 @@ func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		}
 	}
 
-	if app.Spec.RoutingEnabled && svcExists {
+	if res, err := r.upsertMaintenanceBackend(app, req, ctx); err != nil {
+		log.Error(err, "There was an error doing maintenance handling")
+		return res, err
+	}
+
+	if res, err := r.upsertWakeService(app, req, ctx); err != nil {
+		log.Error(err, "There was an error doing wake service handling")
+		return res, err
+	}
+
+	// an offline application's ingress points at the maintenance backend
+	if app.Spec.RoutingEnabled && (svcExists || offline(app)) {
+		if res, err := r.upsertIngress(app, req, ctx); err != nil {
+			log.Error(err, "There was an error doing ingress handling")
+			return res, err
+		}
+	}
+
+	// the status is still updated when the rollout couldn't be tracked, the
+	// request is retried afterwards
+	var rolloutErr error
+	if valid {
+		if rolloutErr = r.trackRollout(deployed, *status, req, ctx); rolloutErr != nil {
+			log.Error(rolloutErr, "There was an error tracking the rollout")
+		}
+	}
+
+	setCondition(status, revisionCondition(revErr))
+	setCondition(status, maintenanceCondition(app))
+	setCondition(status, stoppedCondition(app))
+	setCondition(status, containersCondition(containersErr))
+	setCondition(status, volumesCondition(app, volumesErr))
+	pruned, pruneErr := rev.Prune(req.NamespacedName, r.Retention, ctx)
+	if pruneErr != nil {
+		log.Error(pruneErr, "There was an error pruning revisions")
+	}
+
+	setCondition(status, pruneCondition(pruneErr))
+	if len(pruned) > 0 {
+		now := metav1.Now()
+		status.PrunedRevisions = pruned
+		status.LastPruneTime = &now
+	}
+
+	if err := r.updateStatus(app, *status, req, ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, err := range []error{revErr, digestErr, rolloutErr} {
+		if err != nil {
+			return soonest(result, sleepResult), err
+		}
+	}
+
+	return soonest(result, sleepResult), nil
+}
+
+// reconcileWithoutImage keeps the ingress of an application that has nothing
+// to run yet pointed at the maintenance backend, so its hosts answer with the
+// unavailable page rather than an ingress controller error
+func (r *ApplicationReconciler) reconcileWithoutImage(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	if res, err := r.upsertMaintenanceBackend(app, req, ctx); err != nil {
+		log.Error(err, "There was an error doing maintenance handling")
+		return res, err
+	}
+
+	if res, err := r.upsertWakeService(app, req, ctx); err != nil {
+		log.Error(err, "There was an error doing wake service handling")
+		return res, err
+	}
+
+	if app.Spec.RoutingEnabled {
 		if res, err := r.upsertIngress(app, req, ctx); err != nil {
 			log.Error(err, "There was an error doing ingress handling")
 			return res, err
 		}
 	}
 
-	_ = rev.CreateIfNeeded(req.NamespacedName, ctx)
+	status := app.Status.DeepCopy()
+	status.Sleep = nil
+	setCondition(status, maintenanceCondition(app))
+	setCondition(status, stoppedCondition(app))
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, r.updateStatus(app, *status, req, ctx)
 }
 
 func (r *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&feistyv1alpha1.Application{}).
 		Owns(&v1.Deployment{}).
-		WithEventFilter(predicate.Funcs{
-			UpdateFunc: revisions.RevisionWatchFilter,
+		Watches(&source.Kind{Type: &v12.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.namespaceApplications),
 		}).
 		Complete(r)
 }
@@ -0,0 +1,316 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// DefaultMaintenanceImage serves the maintenance page, it's configured with
+	// the nginx server block below and reads the page on every request
+	DefaultMaintenanceImage = "nginx:1.19-alpine"
+	maintenancePort         = int32(8080)
+	maintenanceServerConfig = `server {
+    listen 8080;
+    root /usr/share/nginx/maintenance;
+    error_page 503 /index.html;
+
+    location = /index.html {
+        internal;
+    }
+
+    location / {
+        return 503;
+    }
+}
+`
+	defaultMaintenancePage = `<!DOCTYPE html>
+<html>
+<head><title>Down for maintenance</title></head>
+<body>
+<h1>Down for maintenance</h1>
+<p>We're working on it and will be back shortly.</p>
+</body>
+</html>
+`
+	stoppedPage = `<!DOCTYPE html>
+<html>
+<head><title>Unavailable</title></head>
+<body>
+<h1>Unavailable</h1>
+<p>This application isn't running at the moment.</p>
+</body>
+</html>
+`
+)
+
+func maintenanceName(app feistyv1alpha1.Application) string {
+	return app.Name + "-maintenance"
+}
+
+// getMaintenanceLabels selects the maintenance backend's pods, they don't have
+// the app labels so the application's Service and Deployments leave them alone
+func getMaintenanceLabels(app feistyv1alpha1.Application) map[string]string {
+	return map[string]string{
+		constants.MaintenanceLabel: app.Name,
+	}
+}
+
+// stopped tells whether the application runs no pods
+func stopped(app feistyv1alpha1.Application) bool {
+	return app.Spec.Replicas <= 0 || app.Spec.Image == ""
+}
+
+// offline tells whether the application's ingress traffic goes to the
+// maintenance backend rather than to its pods
+func offline(app feistyv1alpha1.Application) bool {
+	return app.Spec.Maintenance || stopped(app)
+}
+
+// maintenancePage returns the page the maintenance backend serves, a stopped
+// application that isn't in maintenance says it's unavailable
+func maintenancePage(app feistyv1alpha1.Application) string {
+	switch {
+	case app.Spec.Maintenance && app.Spec.MaintenancePage != "":
+		return app.Spec.MaintenancePage
+	case app.Spec.Maintenance:
+		return defaultMaintenancePage
+	}
+
+	return stoppedPage
+}
+
+func stoppedCondition(app feistyv1alpha1.Application) feistyv1alpha1.ApplicationCondition {
+	switch {
+	case app.Spec.Image == "":
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationStopped,
+			Status:  v12.ConditionTrue,
+			Reason:  "NoImage",
+			Message: "No image was supplied",
+		}
+	case stopped(app):
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationStopped,
+			Status:  v12.ConditionTrue,
+			Reason:  "ScaledToZero",
+			Message: "Ingress traffic is sent to the unavailable page",
+		}
+	}
+
+	return feistyv1alpha1.ApplicationCondition{
+		Type:   feistyv1alpha1.ApplicationStopped,
+		Status: v12.ConditionFalse,
+		Reason: "Running",
+	}
+}
+
+func maintenanceCondition(app feistyv1alpha1.Application) feistyv1alpha1.ApplicationCondition {
+	if app.Spec.Maintenance {
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationMaintenance,
+			Status:  v12.ConditionTrue,
+			Reason:  "MaintenanceOn",
+			Message: "Ingress traffic is sent to the maintenance page",
+		}
+	}
+
+	return feistyv1alpha1.ApplicationCondition{
+		Type:   feistyv1alpha1.ApplicationMaintenance,
+		Status: v12.ConditionFalse,
+		Reason: "MaintenanceOff",
+	}
+}
+
+// upsertMaintenanceBackend runs a small web server answering every request
+// with a 503 and the maintenance page while the application is offline, it's
+// deleted once the application is in maintenance no more and runs again. An
+// application without routing has no ingress to send to it, so it has none.
+func (r *ApplicationReconciler) upsertMaintenanceBackend(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+	meta := metav1.ObjectMeta{
+		Name:      maintenanceName(app),
+		Namespace: app.Namespace,
+	}
+
+	name := types.NamespacedName{Namespace: app.Namespace, Name: meta.Name}
+
+	if !offline(app) || !app.Spec.RoutingEnabled {
+		for _, obj := range []ownedObject{&v12.Service{}, &v1.Deployment{}, &v12.ConfigMap{}} {
+			if err := r.Get(ctx, name, obj); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					log.Error(err, "Unable to fetch maintenance backend")
+					return ctrl.Result{}, err
+				}
+
+				continue
+			}
+
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Could not delete maintenance backend")
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	var cm v12.ConfigMap
+	cmExists := true
+	if err := r.Get(ctx, name, &cm); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch maintenance configmap")
+			return ctrl.Result{}, err
+		}
+
+		cmExists = false
+		cm = v12.ConfigMap{ObjectMeta: meta}
+	}
+
+	cm.Data = map[string]string{
+		"default.conf": maintenanceServerConfig,
+		"index.html":   maintenancePage(app),
+	}
+
+	if err := r.createOrUpdate(app, &cm, cmExists, ctx); err != nil {
+		log.Error(err, "Could not upsert maintenance configmap")
+		return ctrl.Result{}, err
+	}
+
+	var deployment v1.Deployment
+	deploymentExists := true
+	if err := r.Get(ctx, name, &deployment); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch maintenance deployment")
+			return ctrl.Result{}, err
+		}
+
+		deploymentExists = false
+		deployment = v1.Deployment{
+			ObjectMeta: meta,
+			Spec: v1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: getMaintenanceLabels(app),
+				},
+			},
+		}
+	}
+
+	replicas := int32(1)
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Template.ObjectMeta.Labels = getMaintenanceLabels(app)
+	deployment.Spec.Template.Spec.Containers = []v12.Container{{
+		Name:  "maintenance",
+		Image: r.maintenanceImage(),
+		Ports: []v12.ContainerPort{{
+			Name:          "http",
+			ContainerPort: maintenancePort,
+		}},
+		VolumeMounts: []v12.VolumeMount{{
+			Name:      "maintenance",
+			MountPath: "/etc/nginx/conf.d/default.conf",
+			SubPath:   "default.conf",
+		}, {
+			Name:      "maintenance",
+			MountPath: "/usr/share/nginx/maintenance",
+		}},
+	}}
+	// it runs on the same nodes as the application, without spreading
+	deployment.Spec.Template.Spec.NodeSelector, deployment.Spec.Template.Spec.Tolerations = nil, nil
+	if app.Spec.Scheduling != nil {
+		deployment.Spec.Template.Spec.NodeSelector = app.Spec.Scheduling.NodeSelector
+		deployment.Spec.Template.Spec.Tolerations = app.Spec.Scheduling.Tolerations
+	}
+
+	deployment.Spec.Template.Spec.Volumes = []v12.Volume{{
+		Name: "maintenance",
+		VolumeSource: v12.VolumeSource{
+			ConfigMap: &v12.ConfigMapVolumeSource{
+				LocalObjectReference: v12.LocalObjectReference{Name: cm.Name},
+			},
+		},
+	}}
+
+	if err := r.createOrUpdate(app, &deployment, deploymentExists, ctx); err != nil {
+		log.Error(err, "Could not upsert maintenance deployment")
+		return ctrl.Result{}, err
+	}
+
+	var svc v12.Service
+	svcExists := true
+	if err := r.Get(ctx, name, &svc); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch maintenance svc")
+			return ctrl.Result{}, err
+		}
+
+		svcExists = false
+		svc = v12.Service{ObjectMeta: meta}
+	}
+
+	svc.Spec.Type = "ClusterIP"
+	svc.Spec.Selector = getMaintenanceLabels(app)
+	svc.Spec.Ports = []v12.ServicePort{{
+		Name:       "http",
+		Protocol:   "TCP",
+		Port:       defaultExposedPort,
+		TargetPort: intstr.FromInt(int(maintenancePort)),
+	}}
+
+	if err := r.createOrUpdate(app, &svc, svcExists, ctx); err != nil {
+		log.Error(err, "Could not upsert maintenance svc")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *ApplicationReconciler) maintenanceImage() string {
+	if r.MaintenanceImage != "" {
+		return r.MaintenanceImage
+	}
+
+	return DefaultMaintenanceImage
+}
+
+type ownedObject interface {
+	metav1.Object
+	runtime.Object
+}
+
+// createOrUpdate saves an object the application owns
+func (r *ApplicationReconciler) createOrUpdate(app feistyv1alpha1.Application, obj ownedObject, exists bool, ctx context.Context) error {
+	if exists {
+		return r.Update(ctx, obj)
+	}
+
+	_ = ctrl.SetControllerReference(&app, obj, r.Scheme)
+
+	return r.Create(ctx, obj)
+}
@@ -0,0 +1,271 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	"github.com/mrferos/feisty/revisions"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var autoRollbackAuthor = "feisty-controller"
+
+// deploymentPhase works out the rollout phase of the deployment's current
+// pod template, along with a message explaining it
+func deploymentPhase(deployment v1.Deployment) (feistyv1alpha1.RevisionPhase, string) {
+	if deployment.Generation != deployment.Status.ObservedGeneration {
+		return feistyv1alpha1.RevisionRollingOut, "Waiting for the deployment to be updated"
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == v1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return feistyv1alpha1.RevisionFailed, condition.Message
+		}
+	}
+
+	replicas := int32(0)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return feistyv1alpha1.RevisionRollingOut, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		return feistyv1alpha1.RevisionRollingOut, fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return feistyv1alpha1.RevisionRollingOut, fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas)
+	}
+
+	return feistyv1alpha1.RevisionSucceeded, fmt.Sprintf("%d replicas available", status.AvailableReplicas)
+}
+
+// rolloutPhase works out the rollout phase of the application's current
+// revision, from the canary when there is one and otherwise from the
+// Deployment rolling it out. The Deployment may come from a cache that hasn't
+// caught up with the revision yet, it only counts once its pods are the
+// revision's: labelled with it, or the same pods when the revision didn't
+// replace them (e.g. a scale).
+func (r *ApplicationReconciler) rolloutPhase(app feistyv1alpha1.Application, rev feistyv1alpha1.ApplicationRevision, status feistyv1alpha1.ApplicationStatus, ctx context.Context) (feistyv1alpha1.RevisionPhase, string, error) {
+	if canary := status.Canary; canary != nil {
+		switch {
+		case canary.CanaryDeployment != "":
+			return feistyv1alpha1.RevisionRollingOut, canary.Message, nil
+		case canary.AbortedDeployment != "":
+			return feistyv1alpha1.RevisionFailed, canary.Message, nil
+		}
+	}
+
+	var deployment v1.Deployment
+	if err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: status.DeploymentName(app.Name)}, &deployment); err != nil {
+		return "", "", err
+	}
+
+	hash, err := revisions.ContentHash(rev)
+	if err != nil {
+		return "", "", err
+	}
+
+	if deployment.Spec.Template.Labels[constants.RevisionHashLabel] != hash && !runsApplication(app, deployment) {
+		return feistyv1alpha1.RevisionRollingOut, "Waiting for the deployment to be updated", nil
+	}
+
+	phase, message := deploymentPhase(deployment)
+
+	return phase, message, nil
+}
+
+func (r *ApplicationReconciler) setRevisionPhase(rev feistyv1alpha1.ApplicationRevision, phase feistyv1alpha1.RevisionPhase, message string, ctx context.Context) error {
+	if rev.Status.Phase == phase && rev.Status.Message == message {
+		return nil
+	}
+
+	patch := client.MergeFrom(rev.DeepCopy())
+	if rev.Status.Phase != phase {
+		now := metav1.Now()
+		rev.Status.LastTransitionTime = &now
+	}
+
+	rev.Status.Phase = phase
+	rev.Status.Message = message
+
+	return r.Status().Patch(ctx, &rev, patch)
+}
+
+// trackRollout records the rollout phase of the application's current
+// revision from the Deployment rolling it out, marks older revisions that
+// never finished rolling out as superseded and, when enabled, rolls back a
+// failed rollout
+func (r *ApplicationReconciler) trackRollout(app feistyv1alpha1.Application, status feistyv1alpha1.ApplicationStatus, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("application", req.NamespacedName)
+	rev := revisions.Revision{
+		Client: r.Client,
+		Log:    r.Log,
+	}
+
+	current, err := revisions.CurrentRevisionNumber(app)
+	if err != nil || current == 0 {
+		return err
+	}
+
+	numbered, err := rev.ListForApp(app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to list revisions")
+		return err
+	}
+
+	var currentRev *feistyv1alpha1.ApplicationRevision
+	for i, n := range numbered {
+		switch {
+		case n.Number == current:
+			currentRev = &numbered[i].Revision
+		case n.Number < current:
+			phase := n.Revision.Status.Phase
+			if phase == "" || phase == feistyv1alpha1.RevisionPending || phase == feistyv1alpha1.RevisionRollingOut {
+				message := fmt.Sprintf("Replaced by %s before its rollout finished", revisions.RevisionName(app.Name, current))
+				if err := r.setRevisionPhase(n.Revision, feistyv1alpha1.RevisionSuperseded, message, ctx); err != nil {
+					log.Error(err, "Could not update revision status", "revisionName", n.Revision.Name)
+					return err
+				}
+			}
+		}
+	}
+
+	if currentRev == nil {
+		return nil
+	}
+
+	// a finished rollout stays finished, a restart or any other change makes
+	// a new revision
+	if currentRev.Status.Phase == feistyv1alpha1.RevisionSucceeded {
+		return nil
+	}
+
+	phase, message := currentRev.Status.Phase, currentRev.Status.Message
+	if phase != feistyv1alpha1.RevisionFailed {
+		phase, message = feistyv1alpha1.RevisionPending, "Waiting for an image"
+		if app.Spec.Image != "" {
+			phase, message, err = r.rolloutPhase(app, *currentRev, status, ctx)
+			if err != nil {
+				return client.IgnoreNotFound(err)
+			}
+		}
+
+		if err := r.setRevisionPhase(*currentRev, phase, message, ctx); err != nil {
+			log.Error(err, "Could not update revision status", "revisionName", currentRev.Name)
+			return err
+		}
+
+		if phase != feistyv1alpha1.RevisionFailed {
+			return nil
+		}
+
+		log.Info("Rollout failed", "revisionName", currentRev.Name, "reason", message)
+		r.Recorder.Eventf(&app, v12.EventTypeWarning, "RolloutFailed", "Rollout of %s failed: %s", currentRev.Name, message)
+	}
+
+	// rolling back a failed rollback would just go around in circles
+	if !app.Spec.AutoRollback || currentRev.Spec.Change.Cause == revisions.CauseRollback {
+		return nil
+	}
+
+	for _, n := range numbered {
+		if n.Number < current && n.Revision.Status.Phase == feistyv1alpha1.RevisionSucceeded {
+			return r.rollback(app, *currentRev, n.Revision, message, req, ctx)
+		}
+	}
+
+	log.Info("No succeeded revision to roll back to", "revisionName", currentRev.Name)
+
+	return nil
+}
+
+func (r *ApplicationReconciler) rollback(app feistyv1alpha1.Application, failed feistyv1alpha1.ApplicationRevision, target feistyv1alpha1.ApplicationRevision, reason string, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	// the app passed to Reconcile may be stale by now, e.g. after a new revision
+	if err := r.Get(ctx, req.NamespacedName, &app); err != nil {
+		log.Error(err, "Unable to fetch Application")
+		return err
+	}
+
+	var cfg feistyv1alpha1.ApplicationConfig
+	cfgExists := true
+	if err := r.Get(ctx, req.NamespacedName, &cfg); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch ApplicationConfig")
+			return err
+		}
+
+		cfgExists = false
+	}
+
+	currentSpec := app.Spec.DeepCopy()
+	revisions.Restore(target, &app, &cfg)
+
+	// already rolled back, the new revision just hasn't been made yet
+	if apiequality.Semantic.DeepEqual(*currentSpec, app.Spec) {
+		return nil
+	}
+
+	annotations := map[string]string{
+		revisions.ChangeAuthorAnnotation:  autoRollbackAuthor,
+		revisions.ChangeCauseAnnotation:   revisions.CauseRollback,
+		revisions.ChangeMessageAnnotation: fmt.Sprintf("Automatic rollback from %s: %s", failed.Name, reason),
+		revisions.ChangeIDAnnotation:      revisions.NewChangeID(),
+	}
+
+	for _, meta := range []*metav1.ObjectMeta{&app.ObjectMeta, &cfg.ObjectMeta} {
+		if meta.Annotations == nil {
+			meta.Annotations = map[string]string{}
+		}
+
+		for k, v := range annotations {
+			meta.Annotations[k] = v
+		}
+
+		delete(meta.Annotations, revisions.ChangePromotedFromAnnotation)
+	}
+
+	if cfgExists {
+		if err := r.Update(ctx, &cfg); err != nil {
+			log.Error(err, "Could not restore config for rollback", "revisionName", target.Name)
+			return err
+		}
+	}
+
+	if err := r.Update(ctx, &app); err != nil {
+		log.Error(err, "Could not roll back application", "revisionName", target.Name)
+		return err
+	}
+
+	log.Info("Rolled back failed rollout", "failedRevision", failed.Name, "revisionName", target.Name)
+	r.Recorder.Eventf(&app, v12.EventTypeNormal, "RolledBack", "Rolled back from %s to %s after its rollout failed", failed.Name, target.Name)
+
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	v12 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// spreadTopologyKey is the node label the spread shortcut keeps pods apart by
+var spreadTopologyKey = "kubernetes.io/hostname"
+
+// schedulingDefaults reads the scheduling defaults of a namespace
+func (r *ApplicationReconciler) schedulingDefaults(namespace string, ctx context.Context) (*feistyv1alpha1.Scheduling, error) {
+	var ns v12.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	value := ns.Annotations[constants.SchedulingAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+
+	var defaults feistyv1alpha1.Scheduling
+	if err := json.Unmarshal([]byte(value), &defaults); err != nil {
+		return nil, fmt.Errorf("the %s annotation of namespace %s isn't valid: %v", constants.SchedulingAnnotation, namespace, err)
+	}
+
+	return &defaults, nil
+}
+
+// mergeScheduling lays the application's scheduling over the namespace
+// defaults: node selector keys and tolerations add up, the rest replaces the
+// defaults when it's set
+func mergeScheduling(defaults *feistyv1alpha1.Scheduling, scheduling *feistyv1alpha1.Scheduling) *feistyv1alpha1.Scheduling {
+	if defaults == nil {
+		return scheduling
+	}
+
+	merged := defaults.DeepCopy()
+	if scheduling == nil {
+		return merged
+	}
+
+	for k, v := range scheduling.NodeSelector {
+		if merged.NodeSelector == nil {
+			merged.NodeSelector = map[string]string{}
+		}
+
+		merged.NodeSelector[k] = v
+	}
+
+	for _, toleration := range scheduling.Tolerations {
+		duplicate := false
+		for _, existing := range merged.Tolerations {
+			duplicate = duplicate || apiequality.Semantic.DeepEqual(existing, toleration)
+		}
+
+		if !duplicate {
+			merged.Tolerations = append(merged.Tolerations, toleration)
+		}
+	}
+
+	if scheduling.Affinity != nil {
+		merged.Affinity = scheduling.Affinity.DeepCopy()
+	}
+
+	if scheduling.TopologySpread != nil {
+		merged.TopologySpread = scheduling.TopologySpread
+	}
+
+	if scheduling.Spread != nil {
+		merged.Spread = scheduling.Spread
+	}
+
+	return merged
+}
+
+// applyScheduling sets where the pods of a pod template may run
+func applyScheduling(app feistyv1alpha1.Application, template *v12.PodTemplateSpec) {
+	scheduling := app.Spec.Scheduling
+	if scheduling == nil {
+		scheduling = &feistyv1alpha1.Scheduling{}
+	}
+
+	template.Spec.NodeSelector = scheduling.NodeSelector
+	template.Spec.Tolerations = scheduling.Tolerations
+	template.Spec.Affinity = scheduling.Affinity.DeepCopy()
+
+	template.Spec.TopologySpreadConstraints = nil
+	for _, spread := range scheduling.TopologySpread {
+		constraint := v12.TopologySpreadConstraint{
+			MaxSkew:           spread.MaxSkew,
+			TopologyKey:       spread.TopologyKey,
+			WhenUnsatisfiable: spread.WhenUnsatisfiable,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: getAppLabels(app),
+			},
+		}
+
+		if constraint.MaxSkew == 0 {
+			constraint.MaxSkew = 1
+		}
+
+		if constraint.WhenUnsatisfiable == "" {
+			constraint.WhenUnsatisfiable = v12.DoNotSchedule
+		}
+
+		template.Spec.TopologySpreadConstraints = append(template.Spec.TopologySpreadConstraints, constraint)
+	}
+
+	// it's a preference so there can be more replicas than nodes
+	if scheduling.Spread != nil && *scheduling.Spread {
+		if template.Spec.Affinity == nil {
+			template.Spec.Affinity = &v12.Affinity{}
+		}
+
+		if template.Spec.Affinity.PodAntiAffinity == nil {
+			template.Spec.Affinity.PodAntiAffinity = &v12.PodAntiAffinity{}
+		}
+
+		antiAffinity := template.Spec.Affinity.PodAntiAffinity
+		antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, v12.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: v12.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: getAppLabels(app),
+				},
+				TopologyKey: spreadTopologyKey,
+			},
+		})
+	}
+}
+
+// namespaceApplications reconciles the applications of a namespace when its
+// scheduling defaults change
+func (r *ApplicationReconciler) namespaceApplications(obj handler.MapObject) []reconcile.Request {
+	var apps feistyv1alpha1.ApplicationList
+	if err := r.List(context.Background(), &apps, client.InNamespace(obj.Meta.GetName())); err != nil {
+		r.Log.Error(err, "Unable to list applications", "namespace", obj.Meta.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, app := range apps.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: app.Namespace, Name: app.Name},
+		})
+	}
+
+	return requests
+}
@@ -0,0 +1,99 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	v12 "k8s.io/api/core/v1"
+)
+
+func TestMergeScheduling(t *testing.T) {
+	yes, no := true, false
+	production := v12.Toleration{Key: "pool", Operator: v12.TolerationOpEqual, Value: "production", Effect: v12.TaintEffectNoSchedule}
+	gpu := v12.Toleration{Key: "gpu", Operator: v12.TolerationOpExists}
+	zone := []feistyv1alpha1.TopologySpread{{TopologyKey: "topology.kubernetes.io/zone"}}
+	host := []feistyv1alpha1.TopologySpread{{TopologyKey: "kubernetes.io/hostname"}}
+
+	tests := []struct {
+		name       string
+		defaults   *feistyv1alpha1.Scheduling
+		scheduling *feistyv1alpha1.Scheduling
+		want       *feistyv1alpha1.Scheduling
+	}{
+		{
+			name: "no defaults",
+			scheduling: &feistyv1alpha1.Scheduling{
+				NodeSelector: map[string]string{"disk": "ssd"},
+			},
+			want: &feistyv1alpha1.Scheduling{
+				NodeSelector: map[string]string{"disk": "ssd"},
+			},
+		},
+		{
+			name:     "only defaults",
+			defaults: &feistyv1alpha1.Scheduling{Tolerations: []v12.Toleration{production}, Spread: &yes},
+			want:     &feistyv1alpha1.Scheduling{Tolerations: []v12.Toleration{production}, Spread: &yes},
+		},
+		{
+			name: "node selectors and tolerations add up",
+			defaults: &feistyv1alpha1.Scheduling{
+				NodeSelector: map[string]string{"pool": "production", "disk": "hdd"},
+				Tolerations:  []v12.Toleration{production},
+			},
+			scheduling: &feistyv1alpha1.Scheduling{
+				NodeSelector: map[string]string{"disk": "ssd"},
+				Tolerations:  []v12.Toleration{production, gpu},
+			},
+			want: &feistyv1alpha1.Scheduling{
+				NodeSelector: map[string]string{"pool": "production", "disk": "ssd"},
+				Tolerations:  []v12.Toleration{production, gpu},
+			},
+		},
+		{
+			name:       "the rest replaces the defaults when it's set",
+			defaults:   &feistyv1alpha1.Scheduling{TopologySpread: zone, Spread: &yes},
+			scheduling: &feistyv1alpha1.Scheduling{TopologySpread: host, Spread: &no},
+			want:       &feistyv1alpha1.Scheduling{TopologySpread: host, Spread: &no},
+		},
+		{
+			name:       "unset options keep the defaults",
+			defaults:   &feistyv1alpha1.Scheduling{TopologySpread: zone, Spread: &yes},
+			scheduling: &feistyv1alpha1.Scheduling{NodeSelector: map[string]string{"disk": "ssd"}},
+			want:       &feistyv1alpha1.Scheduling{NodeSelector: map[string]string{"disk": "ssd"}, TopologySpread: zone, Spread: &yes},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before *feistyv1alpha1.Scheduling
+			if tt.defaults != nil {
+				before = tt.defaults.DeepCopy()
+			}
+
+			if got := mergeScheduling(tt.defaults, tt.scheduling); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.defaults, before) {
+				t.Errorf("the defaults were changed to %+v", tt.defaults)
+			}
+		})
+	}
+}
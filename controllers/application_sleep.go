@@ -0,0 +1,147 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func wakeName(app feistyv1alpha1.Application) string {
+	return app.Name + "-wake"
+}
+
+// asleep tells whether the application is scaled to zero for being idle
+func asleep(app feistyv1alpha1.Application) bool {
+	return app.Status.Sleep != nil && app.Status.Sleep.Asleep
+}
+
+// sleeps tells whether the application sleeps when idle, its ingress traffic
+// then goes through the wake-up proxy which reports the requests it gets. An
+// offline application gets no requests to report.
+func (r *ApplicationReconciler) sleeps(app feistyv1alpha1.Application) bool {
+	return r.WakeProxyService != "" && app.Spec.SleepAfter != nil && app.Spec.SleepAfter.Duration > 0 && !offline(app)
+}
+
+// updateSleep is the activity tracker: it puts the application to sleep once
+// the wake-up proxy reported no request for SleepAfter. The proxy wakes it up
+// again, the next reconcile scales it back up.
+func (r *ApplicationReconciler) updateSleep(app feistyv1alpha1.Application, status *feistyv1alpha1.ApplicationStatus) ctrl.Result {
+	if !r.sleeps(app) {
+		status.Sleep = nil
+		return ctrl.Result{}
+	}
+
+	if status.Sleep == nil {
+		status.Sleep = &feistyv1alpha1.SleepStatus{}
+	}
+
+	s := status.Sleep
+	// the idle time counts from when sleeping was enabled
+	if s.LastActivityTime == nil {
+		now := metav1.Now()
+		s.LastActivityTime = &now
+	}
+
+	if s.Asleep {
+		return ctrl.Result{}
+	}
+
+	idle := time.Since(s.LastActivityTime.Time)
+	if idle < app.Spec.SleepAfter.Duration {
+		return ctrl.Result{RequeueAfter: app.Spec.SleepAfter.Duration - idle}
+	}
+
+	r.Recorder.Eventf(&app, v12.EventTypeNormal, "Sleeping", "Scaled down after %s without requests", app.Spec.SleepAfter.Duration)
+
+	now := metav1.Now()
+	s.Asleep = true
+	s.SleepTime = &now
+
+	// the Deployments are scaled down once the status says it's asleep
+	return ctrl.Result{Requeue: true}
+}
+
+// upsertWakeService points the Service the ingress sends traffic to while the
+// application sleeps when idle at the wake-up proxy, which runs next to the
+// operator and finds the application by host
+func (r *ApplicationReconciler) upsertWakeService(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (ctrl.Result, error) {
+	log := r.Log.WithValues("application", req.NamespacedName)
+	name := types.NamespacedName{Namespace: app.Namespace, Name: wakeName(app)}
+
+	var svc v12.Service
+	exists := true
+	if err := r.Get(ctx, name, &svc); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch wake svc")
+			return ctrl.Result{}, err
+		}
+
+		exists = false
+		svc = v12.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name.Name,
+				Namespace: name.Namespace,
+			},
+		}
+	}
+
+	if !r.sleeps(app) {
+		if exists {
+			if err := r.Delete(ctx, &svc); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Could not delete wake svc")
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	svc.Spec.Type = v12.ServiceTypeExternalName
+	svc.Spec.ExternalName = r.WakeProxyService
+	svc.Spec.Ports = []v12.ServicePort{{
+		Name:       "http",
+		Protocol:   "TCP",
+		Port:       defaultExposedPort,
+		TargetPort: intstr.FromInt(int(defaultExposedPort)),
+	}}
+
+	if err := r.createOrUpdate(app, &svc, exists, ctx); err != nil {
+		log.Error(err, "Could not upsert wake svc")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// soonest combines the results of two reconcile steps
+func soonest(a ctrl.Result, b ctrl.Result) ctrl.Result {
+	result := ctrl.Result{Requeue: a.Requeue || b.Requeue, RequeueAfter: a.RequeueAfter}
+	if b.RequeueAfter > 0 && (a.RequeueAfter == 0 || b.RequeueAfter < a.RequeueAfter) {
+		result.RequeueAfter = b.RequeueAfter
+	}
+
+	return result
+}
@@ -0,0 +1,323 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// volumeFileMode is the API server default for ConfigMap and Secret volumes,
+// it's set explicitly so the pod template doesn't look changed
+var volumeFileMode = int32(0644)
+
+func volumeClaimName(app feistyv1alpha1.Application, volume feistyv1alpha1.ApplicationVolume) string {
+	return app.Name + "-" + volume.Name
+}
+
+func accessMode(volume feistyv1alpha1.ApplicationVolume) v12.PersistentVolumeAccessMode {
+	if volume.Persistent.AccessMode != "" {
+		return volume.Persistent.AccessMode
+	}
+
+	return v12.ReadWriteOnce
+}
+
+// oneNodeVolume returns the first volume of the application only one node
+// can attach at a time, new pods can't start until the old ones released it
+func oneNodeVolume(app feistyv1alpha1.Application) *feistyv1alpha1.ApplicationVolume {
+	for i, volume := range app.Spec.Volumes {
+		if volume.Persistent != nil && accessMode(volume) == v12.ReadWriteOnce {
+			return &app.Spec.Volumes[i]
+		}
+	}
+
+	return nil
+}
+
+func attachesToOneNode(app feistyv1alpha1.Application) bool {
+	return oneNodeVolume(app) != nil
+}
+
+// strategyOf returns how the application's revisions are rolled out. The
+// bluegreen and canary strategies run two revisions side by side, which a
+// volume attached to one node doesn't allow.
+func strategyOf(app feistyv1alpha1.Application) feistyv1alpha1.DeploymentStrategyType {
+	if attachesToOneNode(app) {
+		return feistyv1alpha1.RollingStrategy
+	}
+
+	return app.Spec.Strategy
+}
+
+// validateVolumes makes sure every volume has a single source and a name of
+// its own, and that the containers only mount declared volumes
+func validateVolumes(app feistyv1alpha1.Application) error {
+	names := map[string]bool{}
+	for _, volume := range app.Spec.Volumes {
+		if names[volume.Name] {
+			return fmt.Errorf("volume name %s is used more than once", volume.Name)
+		}
+
+		names[volume.Name] = true
+
+		sources := 0
+		for _, set := range []bool{volume.Persistent != nil, volume.EmptyDir != nil, volume.ConfigMap != "", volume.Secret != ""} {
+			if set {
+				sources++
+			}
+		}
+
+		if sources != 1 {
+			return fmt.Errorf("volume %s needs exactly one of persistent, emptyDir, configMap or secret", volume.Name)
+		}
+
+		if volume.Persistent != nil && volume.Persistent.Size.IsZero() {
+			return fmt.Errorf("persistent volume %s has no size", volume.Name)
+		}
+	}
+
+	// the pods of the other replicas would be scheduled on other nodes and
+	// wait forever for the volume
+	if volume := oneNodeVolume(app); volume != nil && app.Spec.Replicas > 1 {
+		return fmt.Errorf("volume %s can only be attached to one node, it can't be shared by %d replicas, use the %s access mode or a single replica",
+			volume.Name, app.Spec.Replicas, v12.ReadWriteMany)
+	}
+
+	for _, extra := range append(append([]feistyv1alpha1.ExtraContainer{}, app.Spec.Sidecars...), app.Spec.InitContainers...) {
+		for _, mount := range extra.VolumeMounts {
+			if !names[mount.Name] {
+				return fmt.Errorf("container %s mounts volume %s which isn't declared", extra.Name, mount.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func volumesCondition(app feistyv1alpha1.Application, err error) feistyv1alpha1.ApplicationCondition {
+	if err != nil {
+		return feistyv1alpha1.ApplicationCondition{
+			Type:    feistyv1alpha1.ApplicationVolumesReady,
+			Status:  v12.ConditionFalse,
+			Reason:  "InvalidVolumes",
+			Message: err.Error(),
+		}
+	}
+
+	if strategy := app.Spec.Strategy; strategy != "" && strategy != strategyOf(app) {
+		return feistyv1alpha1.ApplicationCondition{
+			Type:   feistyv1alpha1.ApplicationVolumesReady,
+			Status: v12.ConditionTrue,
+			Reason: feistyv1alpha1.StrategyOverriddenReason,
+			Message: fmt.Sprintf("Rolling out with the %s strategy, the %s strategy runs two releases side by side and volume %s can only be attached to one node",
+				strategyOf(app), strategy, oneNodeVolume(app).Name),
+		}
+	}
+
+	return feistyv1alpha1.ApplicationCondition{
+		Type:   feistyv1alpha1.ApplicationVolumesReady,
+		Status: v12.ConditionTrue,
+		Reason: "VolumesReady",
+	}
+}
+
+func podVolume(app feistyv1alpha1.Application, volume feistyv1alpha1.ApplicationVolume) v12.Volume {
+	source := v12.VolumeSource{}
+	switch {
+	case volume.Persistent != nil:
+		source.PersistentVolumeClaim = &v12.PersistentVolumeClaimVolumeSource{
+			ClaimName: volumeClaimName(app, volume),
+			ReadOnly:  volume.ReadOnly,
+		}
+	case volume.EmptyDir != nil:
+		source.EmptyDir = &v12.EmptyDirVolumeSource{
+			Medium:    volume.EmptyDir.Medium,
+			SizeLimit: volume.EmptyDir.SizeLimit,
+		}
+	case volume.ConfigMap != "":
+		source.ConfigMap = &v12.ConfigMapVolumeSource{
+			LocalObjectReference: v12.LocalObjectReference{Name: volume.ConfigMap},
+			DefaultMode:          &volumeFileMode,
+		}
+	case volume.Secret != "":
+		source.Secret = &v12.SecretVolumeSource{
+			SecretName:  volume.Secret,
+			DefaultMode: &volumeFileMode,
+		}
+	}
+
+	return v12.Volume{Name: volume.Name, VolumeSource: source}
+}
+
+// appVolumeMounts returns where the application's container mounts its volumes
+func appVolumeMounts(app feistyv1alpha1.Application) []v12.VolumeMount {
+	var mounts []v12.VolumeMount
+	for _, volume := range app.Spec.Volumes {
+		if volume.MountPath != "" {
+			mounts = append(mounts, v12.VolumeMount{
+				Name:      volume.Name,
+				MountPath: volume.MountPath,
+				ReadOnly:  volume.ReadOnly,
+			})
+		}
+	}
+
+	return mounts
+}
+
+// applyVolumes sets the application's volumes on a pod template, volumes
+// someone else added are left alone like injected containers are
+func applyVolumes(app feistyv1alpha1.Application, template *v12.PodTemplateSpec) {
+	previous := map[string]bool{}
+	if template.ObjectMeta.Annotations[constants.VolumesAnnotation] != "" {
+		for _, name := range strings.Split(template.ObjectMeta.Annotations[constants.VolumesAnnotation], ",") {
+			previous[name] = true
+		}
+	}
+
+	declared := map[string]bool{}
+	var volumes []v12.Volume
+	var managed []string
+	for _, volume := range app.Spec.Volumes {
+		volumes = append(volumes, podVolume(app, volume))
+		declared[volume.Name] = true
+		managed = append(managed, volume.Name)
+	}
+
+	for _, volume := range template.Spec.Volumes {
+		if !declared[volume.Name] && !previous[volume.Name] {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	template.Spec.Volumes = volumes
+	if len(managed) > 0 {
+		if template.ObjectMeta.Annotations == nil {
+			template.ObjectMeta.Annotations = map[string]string{}
+		}
+
+		template.ObjectMeta.Annotations[constants.VolumesAnnotation] = strings.Join(managed, ",")
+	} else {
+		delete(template.ObjectMeta.Annotations, constants.VolumesAnnotation)
+	}
+}
+
+// upsertVolumeClaims makes the PersistentVolumeClaims of the application's
+// persistent volumes. A claim is owned by the application, and deleted with
+// it, unless the volume is kept. Claims only grow, shrinking isn't supported.
+func (r *ApplicationReconciler) upsertVolumeClaims(app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("application", req.NamespacedName)
+
+	declared := map[string]bool{}
+	for _, volume := range app.Spec.Volumes {
+		if volume.Persistent == nil {
+			continue
+		}
+
+		name := types.NamespacedName{Namespace: app.Namespace, Name: volumeClaimName(app, volume)}
+		declared[name.Name] = true
+
+		var claim v12.PersistentVolumeClaim
+		exists := true
+		if err := r.Get(ctx, name, &claim); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Unable to fetch pvc", "pvcName", name.Name)
+				return err
+			}
+
+			exists = false
+			claim = v12.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name.Name,
+					Namespace: name.Namespace,
+					Labels:    getAppLabels(app),
+				},
+				Spec: v12.PersistentVolumeClaimSpec{
+					AccessModes:      []v12.PersistentVolumeAccessMode{accessMode(volume)},
+					StorageClassName: volume.Persistent.StorageClassName,
+				},
+			}
+			claim.Labels[constants.VolumeLabel] = volume.Name
+		} else if owner := metav1.GetControllerOf(&claim); owner != nil && owner.UID != app.UID {
+			err := fmt.Errorf("pvc %s belongs to another %s", name.Name, owner.Kind)
+			log.Error(err, "Could not adopt pvc", "pvcName", name.Name)
+			return err
+		}
+
+		requested := claim.Spec.Resources.Requests[v12.ResourceStorage]
+		if !exists || volume.Persistent.Size.Cmp(requested) > 0 {
+			claim.Spec.Resources.Requests = v12.ResourceList{v12.ResourceStorage: volume.Persistent.Size}
+		}
+
+		// a kept claim outlives the application, a claim that isn't kept anymore
+		// is adopted again
+		var owners []metav1.OwnerReference
+		for _, owner := range claim.OwnerReferences {
+			if owner.UID != app.UID {
+				owners = append(owners, owner)
+			}
+		}
+
+		claim.OwnerReferences = owners
+		if !volume.Persistent.Keep {
+			_ = ctrl.SetControllerReference(&app, &claim, r.Scheme)
+		}
+
+		var err error
+		if exists {
+			err = r.Update(ctx, &claim)
+		} else {
+			err = r.Create(ctx, &claim)
+		}
+
+		if err != nil {
+			log.Error(err, "Could not upsert pvc", "pvcName", name.Name)
+			return err
+		}
+	}
+
+	// the claims of volumes that aren't declared anymore go unless they're kept
+	var claims v12.PersistentVolumeClaimList
+	if err := r.List(ctx, &claims, client.InNamespace(app.Namespace), client.MatchingLabels(getAppLabels(app))); err != nil {
+		log.Error(err, "Unable to list pvcs")
+		return err
+	}
+
+	for _, claim := range claims.Items {
+		if declared[claim.Name] || claim.Labels[constants.VolumeLabel] == "" || !metav1.IsControlledBy(&claim, &app) {
+			continue
+		}
+
+		log.Info("Deleting pvc", "pvcName", claim.Name)
+		if err := r.Delete(ctx, &claim); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Could not delete pvc", "pvcName", claim.Name)
+			return err
+		}
+	}
+
+	return nil
+}
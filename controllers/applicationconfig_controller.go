@@ -18,17 +18,20 @@ package controllers
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/json"
-	"fmt"
 	"github.com/go-logr/logr"
 	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/hashing"
 	"github.com/mrferos/feisty/revisions"
 	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
 )
 
 // ApplicationConfigReconciler reconciles a ApplicationConfig object
@@ -38,6 +41,8 @@ type ApplicationConfigReconciler struct {
 	Scheme *runtime.Scheme
 }
 
+var secretGCRequeueAfter = 30 * time.Second
+
 type upsertResult struct {
 	secret  *v1.Secret
 	created bool
@@ -45,28 +50,57 @@ type upsertResult struct {
 
 // +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=applicationconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=applicationconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets,verbs=get;list;watch
+
+// getSecretNames returns the name of the Secret holding the config's values
+// along with the name older versions gave it, from an MD5 of the values
+func (r *ApplicationConfigReconciler) getSecretNames(cfg feistyv1alpha1.ApplicationConfig) (string, string, error) {
+	sum, err := hashing.Sum(cfg.Spec.KeyValuePairs)
+	if err != nil {
+		return "", "", err
+	}
+
+	legacySum, err := hashing.LegacyMD5(cfg.Spec.KeyValuePairs)
+	if err != nil {
+		return "", "", err
+	}
+
+	return cfg.Name + "-" + hashing.Short(sum), cfg.Name + "-" + legacySum, nil
+}
 
-func (r *ApplicationConfigReconciler) getSecretId(cfg feistyv1alpha1.ApplicationConfig) (string, error) {
-	keyValueJson, err := json.Marshal(cfg.Spec.KeyValuePairs)
+// resolveSecretName returns the name of the Secret for the config's values.
+// A Secret created by an older version for the same values is reused, so an
+// upgrade doesn't repoint and restart every application.
+func (r *ApplicationConfigReconciler) resolveSecretName(cfg feistyv1alpha1.ApplicationConfig, ctx context.Context) (string, error) {
+	secretName, legacyName, err := r.getSecretNames(cfg)
 	if err != nil {
 		return "", err
 	}
 
-	md5bytes := md5.Sum([]byte(keyValueJson))
+	var secret v1.Secret
+	err = r.Get(ctx, client.ObjectKey{Namespace: cfg.Namespace, Name: secretName}, &secret)
+	if !apierrors.IsNotFound(err) {
+		return secretName, err
+	}
+
+	err = r.Get(ctx, client.ObjectKey{Namespace: cfg.Namespace, Name: legacyName}, &secret)
+	if err == nil && metav1.IsControlledBy(&secret, &cfg) {
+		return legacyName, nil
+	}
 
-	return fmt.Sprintf("%x", md5bytes), nil
+	return secretName, client.IgnoreNotFound(err)
 }
 
 func (r *ApplicationConfigReconciler) upsertSecret(cfg feistyv1alpha1.ApplicationConfig, req ctrl.Request, ctx context.Context) (upsertResult, error) {
 	log := r.Log.WithValues("application", req.NamespacedName)
 
-	secretId, err := r.getSecretId(cfg)
+	secretName, err := r.resolveSecretName(cfg, ctx)
 	upsertState := upsertResult{}
 	if err != nil {
 		return upsertState, err
 	}
 
-	secretName := cfg.Name + "-" + secretId
 	objKey := client.ObjectKey{
 		Namespace: cfg.Namespace,
 		Name:      secretName,
@@ -96,13 +130,13 @@ func (r *ApplicationConfigReconciler) upsertSecret(cfg feistyv1alpha1.Applicatio
 	if doCreate {
 		_ = ctrl.SetControllerReference(&cfg, &secret, r.Scheme)
 		if err := r.Create(ctx, &secret); err != nil {
-			upsertState.created = true
 			log.Error(err, "Could not create secret")
 			return upsertState, err
 		}
+
+		upsertState.created = true
 	} else {
 		if err := r.Update(ctx, &secret); err != nil {
-			upsertState.created = false
 			log.Error(err, "Could not update secret")
 			return upsertState, err
 		}
@@ -111,6 +145,33 @@ func (r *ApplicationConfigReconciler) upsertSecret(cfg feistyv1alpha1.Applicatio
 	return upsertState, nil
 }
 
+func (r *ApplicationConfigReconciler) updateStatus(cfg feistyv1alpha1.ApplicationConfig, secretName string, gc gcResult, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("applicationconfig", req.NamespacedName)
+
+	status := cfg.Status.DeepCopy()
+	status.SecretName = secretName
+	status.RetainedSecrets = gc.retained
+	status.InUseSecrets = gc.inUse
+	if len(gc.deleted) > 0 {
+		now := metav1.Now()
+		status.DeletedSecrets = gc.deleted
+		status.LastGCTime = &now
+	}
+
+	if apiequality.Semantic.DeepEqual(cfg.Status, *status) {
+		return nil
+	}
+
+	patch := client.MergeFrom(cfg.DeepCopy())
+	cfg.Status = *status
+	if err := r.Status().Patch(ctx, &cfg, patch); err != nil {
+		log.Error(err, "Could not update config status")
+		return err
+	}
+
+	return nil
+}
+
 func (r *ApplicationConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("applicationconfig", req.NamespacedName)
@@ -142,7 +203,25 @@ func (r *ApplicationConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 		}
 
 		if upsertState.created {
-			_ = rev.CreateIfNeeded(req.NamespacedName, ctx)
+			if _, err := rev.CreateIfNeeded(req.NamespacedName, ctx); err != nil {
+				log.Error(err, "There was an error creating the revision")
+				return ctrl.Result{}, err
+			}
+		}
+
+		gc, err := r.collectSecrets(cfg, app, req, ctx)
+		if err != nil {
+			log.Error(err, "There was an error collecting stale config secrets")
+			return ctrl.Result{}, err
+		}
+
+		if err := r.updateStatus(cfg, upsertState.secret.Name, gc, req, ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// secrets still used by pods are collected once the pods are gone
+		if len(gc.inUse) > 0 {
+			return ctrl.Result{RequeueAfter: secretGCRequeueAfter}, nil
 		}
 	}
 
@@ -153,5 +232,8 @@ func (r *ApplicationConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&feistyv1alpha1.ApplicationConfig{}).
 		Owns(&v1.Secret{}).
+		// applications share their config's name, so changes to them (like
+		// pruned revisions) re-run the secret garbage collection
+		Watches(&source.Kind{Type: &feistyv1alpha1.Application{}}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
@@ -0,0 +1,91 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestConfigChangeCreatesRevision(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = feistyv1alpha1.AddToScheme(scheme)
+
+	name := types.NamespacedName{Namespace: "default", Name: "web"}
+	meta := metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name}
+	app := &feistyv1alpha1.Application{ObjectMeta: meta, Spec: feistyv1alpha1.ApplicationSpec{Image: "nginx"}}
+	cfg := &feistyv1alpha1.ApplicationConfig{ObjectMeta: meta, Spec: feistyv1alpha1.ApplicationConfigSpec{
+		KeyValuePairs: map[string]string{"A": "1"},
+	}}
+
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(scheme, app, cfg)
+	r := &ApplicationConfigReconciler{Client: c, Log: log.NullLogger{}, Scheme: scheme}
+
+	revisionsFor := func(value string) int {
+		t.Helper()
+
+		if _, err := r.Reconcile(ctrl.Request{NamespacedName: name}); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+
+		var revs feistyv1alpha1.ApplicationRevisionList
+		if err := c.List(ctx, &revs, client.InNamespace(name.Namespace), client.MatchingLabels{constants.AppLabel: name.Name}); err != nil {
+			t.Fatalf("list revisions: %v", err)
+		}
+
+		found := false
+		for _, rev := range revs.Items {
+			found = found || rev.Spec.Cfg.KeyValuePairs["A"] == value
+		}
+
+		if !found {
+			t.Errorf("no revision with A=%s in %d revisions", value, len(revs.Items))
+		}
+
+		return len(revs.Items)
+	}
+
+	if got := revisionsFor("1"); got != 1 {
+		t.Errorf("got %d revisions after the first config, want 1", got)
+	}
+
+	if err := c.Get(ctx, name, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Spec.KeyValuePairs["A"] = "2"
+	if err := c.Update(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := revisionsFor("2"); got != 2 {
+		t.Errorf("got %d revisions after the config change, want 2", got)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/revisions"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type gcResult struct {
+	retained []string
+	inUse    []string
+	deleted  []string
+}
+
+// addPodSpecSecretNames adds the names of the Secrets a pod spec uses
+func addPodSpecSecretNames(spec v1.PodSpec, names map[string]bool) {
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			names[volume.Secret.SecretName] = true
+		}
+	}
+
+	containers := append([]v1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				names[envFrom.SecretRef.Name] = true
+			}
+		}
+
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				names[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+}
+
+// podSecretNames returns the names of the Secrets used by pods that haven't
+// finished yet
+func podSecretNames(pods []v1.Pod) map[string]bool {
+	names := map[string]bool{}
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		addPodSpecSecretNames(pod.Spec, names)
+	}
+
+	return names
+}
+
+// templateSecretNames adds the names of the Secrets used by the pod templates
+// of the application's Deployments and their ReplicaSets. Those can have no
+// pods for a while, e.g. when the application is stopped or asleep or a
+// canary was aborted, and still need their Secrets when they're scaled up.
+func (r *ApplicationConfigReconciler) templateSecretNames(app feistyv1alpha1.Application, names map[string]bool, ctx context.Context) error {
+	var deployments appsv1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(app.Namespace)); err != nil {
+		return err
+	}
+
+	owned := map[types.UID]bool{}
+	for _, deployment := range deployments.Items {
+		if metav1.IsControlledBy(&deployment, &app) {
+			owned[deployment.UID] = true
+			addPodSpecSecretNames(deployment.Spec.Template.Spec, names)
+		}
+	}
+
+	var replicaSets appsv1.ReplicaSetList
+	if err := r.List(ctx, &replicaSets, client.InNamespace(app.Namespace)); err != nil {
+		return err
+	}
+
+	for _, replicaSet := range replicaSets.Items {
+		if owner := metav1.GetControllerOf(&replicaSet); owner != nil && owned[owner.UID] {
+			addPodSpecSecretNames(replicaSet.Spec.Template.Spec, names)
+		}
+	}
+
+	return nil
+}
+
+// referencedSecretNames returns the config Secrets the application and its
+// retained revisions point at, so rolling back to any of them still works
+func (r *ApplicationConfigReconciler) referencedSecretNames(cfg feistyv1alpha1.ApplicationConfig, app feistyv1alpha1.Application, ctx context.Context) (map[string]bool, error) {
+	rev := revisions.Revision{
+		Client: r.Client,
+		Log:    r.Log,
+	}
+
+	names := map[string]bool{}
+	if app.Spec.AppConfigRef != "" {
+		names[app.Spec.AppConfigRef] = true
+	}
+
+	numbered, err := rev.ListForApp(app, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range numbered {
+		if n.Revision.Spec.App.AppConfigRef != "" {
+			names[n.Revision.Spec.App.AppConfigRef] = true
+		}
+
+		// either name may hold the revision's values, depending on the version that created it
+		secretName, legacyName, err := r.getSecretNames(feistyv1alpha1.ApplicationConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: cfg.Name},
+			Spec:       n.Revision.Spec.Cfg,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		names[secretName] = true
+		names[legacyName] = true
+	}
+
+	return names, nil
+}
+
+// collectSecrets deletes the config Secrets that are neither referenced by the
+// application or its retained revisions nor used by a running pod or the pod
+// template of one of its Deployments
+func (r *ApplicationConfigReconciler) collectSecrets(cfg feistyv1alpha1.ApplicationConfig, app feistyv1alpha1.Application, req ctrl.Request, ctx context.Context) (gcResult, error) {
+	log := r.Log.WithValues("applicationconfig", req.NamespacedName)
+	result := gcResult{}
+
+	referenced, err := r.referencedSecretNames(cfg, app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to work out which config secrets are referenced")
+		return result, err
+	}
+
+	var secrets v1.SecretList
+	if err := r.List(ctx, &secrets, client.InNamespace(cfg.Namespace)); err != nil {
+		log.Error(err, "Unable to list secrets")
+		return result, err
+	}
+
+	var pods v1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(cfg.Namespace)); err != nil {
+		log.Error(err, "Unable to list pods")
+		return result, err
+	}
+
+	inUse := podSecretNames(pods.Items)
+	if err := r.templateSecretNames(app, inUse, ctx); err != nil {
+		log.Error(err, "Unable to list the application's deployments")
+		return result, err
+	}
+
+	for _, secret := range secrets.Items {
+		if !metav1.IsControlledBy(&secret, &cfg) {
+			continue
+		}
+
+		switch {
+		case secret.Name == app.Spec.AppConfigRef:
+		case referenced[secret.Name]:
+			result.retained = append(result.retained, secret.Name)
+		case inUse[secret.Name]:
+			result.inUse = append(result.inUse, secret.Name)
+		default:
+			if err := r.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Could not delete stale config secret", "secretName", secret.Name)
+				return result, err
+			}
+
+			log.Info("Deleted stale config secret", "secretName", secret.Name)
+			result.deleted = append(result.deleted, secret.Name)
+		}
+	}
+
+	sort.Strings(result.retained)
+	sort.Strings(result.inUse)
+	sort.Strings(result.deleted)
+
+	return result, nil
+}
@@ -0,0 +1,181 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/reviewapps"
+	"github.com/mrferos/feisty/revisions"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// ReviewAppTemplateReconciler deletes the review apps of a ReviewAppTemplate
+// once they expire
+type ReviewAppTemplateReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=reviewapptemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=feisty.paas.feisty.dev,resources=reviewapptemplates/status,verbs=get;update;patch
+
+// deleteReviewApp deletes a review app along with its config and revisions,
+// which aren't owned by the app
+func (r *ReviewAppTemplateReconciler) deleteReviewApp(app feistyv1alpha1.Application, ctx context.Context) error {
+	rev := revisions.Revision{
+		Client: r.Client,
+		Log:    r.Log,
+	}
+
+	numbered, err := rev.ListForApp(app, ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range numbered {
+		if err := r.Delete(ctx, &n.Revision); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	cfg := feistyv1alpha1.ApplicationConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+		},
+	}
+
+	if err := r.Delete(ctx, &cfg); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	return client.IgnoreNotFound(r.Delete(ctx, &app))
+}
+
+func (r *ReviewAppTemplateReconciler) updateStatus(tmpl feistyv1alpha1.ReviewAppTemplate, apps []string, expired []string, req ctrl.Request, ctx context.Context) error {
+	log := r.Log.WithValues("reviewapptemplate", req.NamespacedName)
+
+	status := tmpl.Status.DeepCopy()
+	status.Apps = apps
+	if len(expired) > 0 {
+		now := metav1.Now()
+		status.ExpiredApps = expired
+		status.LastExpiryTime = &now
+	}
+
+	if apiequality.Semantic.DeepEqual(tmpl.Status, *status) {
+		return nil
+	}
+
+	patch := client.MergeFrom(tmpl.DeepCopy())
+	tmpl.Status = *status
+	if err := r.Status().Patch(ctx, &tmpl, patch); err != nil {
+		log.Error(err, "Could not update review app template status")
+		return err
+	}
+
+	return nil
+}
+
+func (r *ReviewAppTemplateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("reviewapptemplate", req.NamespacedName)
+
+	var tmpl feistyv1alpha1.ReviewAppTemplate
+	if err := r.Get(ctx, req.NamespacedName, &tmpl); err != nil {
+		log.Error(err, "Unable to fetch ReviewAppTemplate")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var apps feistyv1alpha1.ApplicationList
+	if err := r.List(ctx, &apps, client.InNamespace(tmpl.Namespace), client.MatchingLabels{reviewapps.TemplateLabel: tmpl.Name}); err != nil {
+		log.Error(err, "Unable to list review apps")
+		return ctrl.Result{}, err
+	}
+
+	var active, expired []string
+	var nextExpiry time.Time
+	for _, app := range apps.Items {
+		if !app.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		expiresAt, ok := reviewapps.ExpiresAt(app, tmpl)
+		if ok && !time.Now().Before(expiresAt) {
+			if err := r.deleteReviewApp(app, ctx); err != nil {
+				log.Error(err, "Could not delete expired review app", "appName", app.Name)
+				return ctrl.Result{}, err
+			}
+
+			log.Info("Deleted expired review app", "appName", app.Name)
+			expired = append(expired, app.Name)
+			continue
+		}
+
+		active = append(active, app.Name)
+		if ok && (nextExpiry.IsZero() || expiresAt.Before(nextExpiry)) {
+			nextExpiry = expiresAt
+		}
+	}
+
+	sort.Strings(active)
+	sort.Strings(expired)
+
+	if err := r.updateStatus(tmpl, active, expired, req, ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if nextExpiry.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: time.Until(nextExpiry)}, nil
+}
+
+func (r *ReviewAppTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&feistyv1alpha1.ReviewAppTemplate{}).
+		// review apps point back at their template with a label
+		Watches(&source.Kind{Type: &feistyv1alpha1.Application{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+				name, ok := obj.Meta.GetLabels()[reviewapps.TemplateLabel]
+				if !ok {
+					return nil
+				}
+
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Namespace: obj.Meta.GetNamespace(),
+					Name:      name,
+				}}}
+			}),
+		}).
+		Complete(r)
+}
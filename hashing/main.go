@@ -0,0 +1,206 @@
+package hashing
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ShortLength is how many characters of a hash are used in object names and
+// label values, which have length limits
+const ShortLength = 16
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// Canonical returns a JSON encoding of obj that only changes when something
+// meaningful changes. Struct fields holding their zero value, which is what an
+// unset field defaults to, are left out so adding a field to a type doesn't
+// change the encoding of existing objects. Map entries are always kept as an
+// empty config value is still a value. Object keys are sorted.
+func Canonical(obj interface{}) ([]byte, error) {
+	value, err := canonicalValue(reflect.ValueOf(obj))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// Sum returns the hex encoded SHA-256 of the canonical encoding of obj
+func Sum(obj interface{}) (string, error) {
+	canonical, err := Canonical(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(canonical)), nil
+}
+
+// Short shortens a hash returned by Sum for use in names and labels
+func Short(sum string) string {
+	if len(sum) <= ShortLength {
+		return sum
+	}
+
+	return sum[:ShortLength]
+}
+
+// LegacyMD5 returns the hash older versions used, the MD5 of the plain JSON
+// encoding of obj. It's only used to find the objects they named with it.
+func LegacyMD5(obj interface{}) (string, error) {
+	plain, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", md5.Sum(plain)), nil
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+func canonicalValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	// types with their own encoding, e.g. metav1.Time or resource.Quantity
+	if v.Type().Implements(jsonMarshaler) || (v.CanAddr() && v.Addr().Type().Implements(jsonMarshaler)) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil, nil
+		}
+
+		encoded, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		var decoded interface{}
+		err = json.Unmarshal(encoded, &decoded)
+
+		return decoded, err
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		return canonicalValue(v.Elem())
+	case reflect.Struct:
+		fields := map[string]interface{}{}
+		if err := addStructFields(fields, v); err != nil {
+			return nil, err
+		}
+
+		return fields, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		entries := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			entry, err := canonicalValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+
+			entries[fmt.Sprint(iter.Key().Interface())] = entry
+		}
+
+		return entries, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		// byte slices are encoded as base64 like encoding/json does
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+
+		items := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := canonicalValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = item
+		}
+
+		return items, nil
+	}
+
+	return v.Interface(), nil
+}
+
+// addStructFields adds the non-empty exported fields of a struct under their
+// JSON names, embedded structs are flattened like encoding/json does
+func addStructFields(fields map[string]interface{}, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+
+			if tagName != "" {
+				name = tagName
+			} else if field.Anonymous {
+				name = ""
+			}
+		} else if field.Anonymous {
+			name = ""
+		}
+
+		fieldValue := v.Field(i)
+		if name == "" && fieldValue.Kind() == reflect.Struct {
+			if err := addStructFields(fields, fieldValue); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		value, err := canonicalValue(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		if isEmpty(value) || reflect.ValueOf(value).IsZero() {
+			continue
+		}
+
+		fields[name] = value
+	}
+
+	return nil
+}
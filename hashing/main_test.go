@@ -0,0 +1,105 @@
+package hashing
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type inner struct {
+	Value string `json:"value,omitempty"`
+}
+
+type Embedded struct {
+	Flattened string `json:"flattened"`
+}
+
+type sample struct {
+	Embedded
+	Name     string            `json:"name"`
+	Count    int               `json:"count,omitempty"`
+	Config   map[string]string `json:"config,omitempty"`
+	Items    []string          `json:"items,omitempty"`
+	Inner    *inner            `json:"inner,omitempty"`
+	Time     *metav1.Time      `json:"time,omitempty"`
+	Ignored  string            `json:"-"`
+	untagged string
+}
+
+func TestCanonical(t *testing.T) {
+	at := metav1.NewTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		want string
+	}{
+		{name: "zero values are left out", obj: sample{}, want: `{}`},
+		{name: "keys are sorted", obj: sample{Name: "app", Count: 2}, want: `{"count":2,"name":"app"}`},
+		{name: "empty map entries are kept", obj: sample{Config: map[string]string{"B": "", "A": "1"}}, want: `{"config":{"A":"1","B":""}}`},
+		{name: "empty maps and slices are left out", obj: sample{Config: map[string]string{}, Items: []string{}}, want: `{}`},
+		{name: "empty nested structs are left out", obj: sample{Inner: &inner{}}, want: `{}`},
+		{name: "nested structs", obj: sample{Inner: &inner{Value: "x"}}, want: `{"inner":{"value":"x"}}`},
+		{name: "embedded structs are flattened", obj: sample{Embedded: Embedded{Flattened: "x"}}, want: `{"flattened":"x"}`},
+		{name: "unexported and ignored fields are left out", obj: sample{Ignored: "x", untagged: "y"}, want: `{}`},
+		{name: "types with their own encoding", obj: sample{Time: &at}, want: `{"time":"2020-01-02T03:04:05Z"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonical(tt.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	a, err := Sum(map[string]string{"A": "1", "B": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Sum(map[string]string{"B": "2", "A": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a != b {
+		t.Errorf("the same map hashed to %s and %s", a, b)
+	}
+
+	if len(a) != 64 {
+		t.Errorf("got a hash of %d characters, want a SHA-256", len(a))
+	}
+
+	if c, _ := Sum(map[string]string{"A": "1", "B": ""}); c == a {
+		t.Errorf("different maps hashed to %s", c)
+	}
+
+	if short := Short(a); len(short) != ShortLength || short != a[:ShortLength] {
+		t.Errorf("Short(%s) = %s", a, short)
+	}
+
+	if short := Short("abc"); short != "abc" {
+		t.Errorf("Short(abc) = %s", short)
+	}
+}
+
+func TestLegacyMD5(t *testing.T) {
+	got, err := LegacyMD5(map[string]string{"A": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// md5 of {"A":"1"}
+	if want := "70973b09e30368d46408746c24f918b8"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -28,6 +29,8 @@ import (
 
 	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
 	"github.com/mrferos/feisty/controllers"
+	"github.com/mrferos/feisty/revisions"
+	"github.com/mrferos/feisty/webhooks"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -46,10 +49,30 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhooks bool
+	var revisionHistoryLimit int
+	var revisionMaxAge time.Duration
+	var maintenanceImage string
+	var wakeProxyService string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks, which record the requesting user as the author of each change. "+
+			"Requires the webhook configuration and serving certificates to be deployed.")
+	flag.IntVar(&revisionHistoryLimit, "revision-history-limit", 0,
+		"The number of revisions to keep per application, 0 keeps all of them. "+
+			"Applications can override this with spec.revisionRetention.")
+	flag.DurationVar(&revisionMaxAge, "revision-max-age", 0,
+		"Revisions older than this are pruned, 0 keeps all of them. "+
+			"Applications can override this with spec.revisionRetention.")
+	flag.StringVar(&maintenanceImage, "maintenance-image", controllers.DefaultMaintenanceImage,
+		"The nginx image serving the maintenance page of applications in maintenance.")
+	flag.StringVar(&wakeProxyService, "wake-proxy-service", "",
+		"The DNS name of the wake-up proxy's Service, e.g. feisty-wakeproxy.feisty-system.svc.cluster.local. "+
+			"Applications only sleep when idle if it's set. All the ingress traffic of an application with "+
+			"sleepAfter set goes through the proxy, awake or not, so run enough replicas of it.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -67,9 +90,16 @@ func main() {
 	}
 
 	if err = (&controllers.ApplicationReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Application"),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Application"),
+		Scheme:           mgr.GetScheme(),
+		Recorder:         mgr.GetEventRecorderFor("application-controller"),
+		MaintenanceImage: maintenanceImage,
+		WakeProxyService: wakeProxyService,
+		Retention: revisions.RetentionPolicy{
+			Count:  revisionHistoryLimit,
+			MaxAge: revisionMaxAge,
+		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
@@ -82,6 +112,17 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ApplicationConfig")
 		os.Exit(1)
 	}
+	if err = (&controllers.ReviewAppTemplateReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ReviewAppTemplate"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ReviewAppTemplate")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		webhooks.Register(mgr.GetWebhookServer(), ctrl.Log.WithName("webhooks"))
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -0,0 +1,124 @@
+package reviewapps
+
+import (
+	"time"
+
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// TemplateLabel holds the ReviewAppTemplate a review app was created from
+	TemplateLabel = constants.FeistyAnnotationPrefix + "review-app-template"
+	// ParentLabel holds the Application a review app was cloned from
+	ParentLabel = constants.FeistyAnnotationPrefix + "review-app-parent"
+	// TTLAnnotation overrides the template's TTL for a single review app
+	TTLAnnotation = constants.FeistyAnnotationPrefix + "review-app-ttl"
+)
+
+// Name returns the name of a review app, e.g. app-pr-42 for the suffix pr-42
+func Name(parent string, suffix string) string {
+	return parent + "-" + suffix
+}
+
+// NewApplication clones the template's parent into a review app. The clone
+// doesn't take over the parent's domains, it gets a host under the template's
+// domain instead.
+func NewApplication(tmpl v1alpha1.ReviewAppTemplate, parent v1alpha1.Application, name string) v1alpha1.Application {
+	spec := *parent.Spec.DeepCopy()
+	spec.AppConfigRef = ""
+	spec.RestartTime = ""
+	spec.Domains = nil
+	spec.RoutingEnabled = false
+
+	if tmpl.Spec.Domain != "" {
+		spec.Domains = []v1alpha1.ApplicationDomain{{Host: name + "." + tmpl.Spec.Domain}}
+		spec.RoutingEnabled = true
+	}
+
+	if tmpl.Spec.Overrides.Image != "" {
+		spec.Image = tmpl.Spec.Overrides.Image
+	}
+
+	if tmpl.Spec.Overrides.Replicas != nil {
+		spec.Replicas = *tmpl.Spec.Overrides.Replicas
+	}
+
+	return v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: tmpl.Namespace,
+			Labels: map[string]string{
+				TemplateLabel: tmpl.Name,
+				ParentLabel:   parent.Name,
+			},
+			// deleting the template deletes its review apps
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "ReviewAppTemplate",
+				Name:       tmpl.Name,
+				UID:        tmpl.UID,
+			}},
+		},
+		Spec: spec,
+	}
+}
+
+// NewConfig returns the config of a review app: a copy of the parent's config,
+// unless the template ignores it, with the template's config set over it. It
+// is owned by the review app so it's deleted along with it.
+func NewConfig(tmpl v1alpha1.ReviewAppTemplate, parentCfg *v1alpha1.ApplicationConfig, app v1alpha1.Application) v1alpha1.ApplicationConfig {
+	keyValuePairs := map[string]string{}
+	if parentCfg != nil && !tmpl.Spec.IgnoreParentConfig {
+		for k, v := range parentCfg.Spec.KeyValuePairs {
+			keyValuePairs[k] = v
+		}
+	}
+
+	for k, v := range tmpl.Spec.Config {
+		keyValuePairs[k] = v
+	}
+
+	return v1alpha1.ApplicationConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+			Labels:    app.Labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "Application",
+				Name:       app.Name,
+				UID:        app.UID,
+			}},
+		},
+		Spec: v1alpha1.ApplicationConfigSpec{
+			KeyValuePairs: keyValuePairs,
+		},
+	}
+}
+
+// TTL returns how long a review app lives, 0 when it doesn't expire
+func TTL(app v1alpha1.Application, tmpl v1alpha1.ReviewAppTemplate) time.Duration {
+	if val, ok := app.Annotations[TTLAnnotation]; ok {
+		if ttl, err := time.ParseDuration(val); err == nil {
+			return ttl
+		}
+	}
+
+	if tmpl.Spec.TTL == nil {
+		return 0
+	}
+
+	return tmpl.Spec.TTL.Duration
+}
+
+// ExpiresAt returns when a review app expires, false when it doesn't
+func ExpiresAt(app v1alpha1.Application, tmpl v1alpha1.ReviewAppTemplate) (time.Time, bool) {
+	ttl := TTL(app, tmpl)
+	if ttl <= 0 {
+		return time.Time{}, false
+	}
+
+	return app.CreationTimestamp.Add(ttl), true
+}
@@ -0,0 +1,239 @@
+package revisions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+)
+
+var (
+	// ChangeAuthorAnnotation holds the user that made the last change to an
+	// Application or ApplicationConfig, it's set by the CLI and overwritten by
+	// the change author webhook when that's enabled
+	ChangeAuthorAnnotation = constants.FeistyAnnotationPrefix + "change-author"
+	// ChangeCauseAnnotation holds the cause of the last change when it can't be
+	// worked out from the change itself, e.g. a rollback
+	ChangeCauseAnnotation = constants.FeistyAnnotationPrefix + "change-cause"
+	// ChangeMessageAnnotation holds the message given with the last change
+	ChangeMessageAnnotation = constants.FeistyAnnotationPrefix + "change-message"
+	// ChangePromotedFromAnnotation holds the release a promoted image came
+	// from, as namespace/revision
+	ChangePromotedFromAnnotation = constants.FeistyAnnotationPrefix + "change-promoted-from"
+	// ChangeIDAnnotation is set to a new value by every request that sets the
+	// other change annotations, so a change that kept it can be told apart
+	// from one that set the same cause or message again
+	ChangeIDAnnotation = constants.FeistyAnnotationPrefix + "change-id"
+
+	ChangeAnnotations = []string{ChangeAuthorAnnotation, ChangeCauseAnnotation, ChangeMessageAnnotation, ChangePromotedFromAnnotation, ChangeIDAnnotation}
+)
+
+// NewChangeID returns a value for the change ID annotation
+func NewChangeID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+const (
+	CauseDeploy   = "deploy"
+	CauseConfig   = "config"
+	CauseScale    = "scale"
+	CauseRollback = "rollback"
+	CauseRestart  = "restart"
+	CauseUpdate   = "update"
+	CausePromote  = "promote"
+)
+
+// describeConfigChange summarises the keys that differ between two configs,
+// values are left out since they're often secret
+func describeConfigChange(prev map[string]string, next map[string]string) string {
+	var set, removed []string
+	for k, v := range next {
+		if prevVal, ok := prev[k]; !ok || prevVal != v {
+			set = append(set, k)
+		}
+	}
+
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+
+	sort.Strings(set)
+	sort.Strings(removed)
+
+	var parts []string
+	if len(set) > 0 {
+		parts = append(parts, "Set "+strings.Join(set, ", "))
+	}
+
+	if len(removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(removed, ", "))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// describeAppChange summarises the app spec changes and works out their cause.
+// The config reference is left out as it follows config changes.
+func describeAppChange(prev v1alpha1.ApplicationSpec, next v1alpha1.ApplicationSpec) (string, string) {
+	var parts []string
+	cause := ""
+	setCause := func(c string) {
+		if cause == "" {
+			cause = c
+		}
+	}
+
+	if prev.Image != next.Image {
+		parts = append(parts, "Deployed "+next.Image)
+		setCause(CauseDeploy)
+	}
+
+	if prev.RestartTime != next.RestartTime {
+		parts = append(parts, "Restarted")
+		setCause(CauseRestart)
+	}
+
+	switch {
+	case !Stopped(prev) && Stopped(next):
+		parts = append(parts, "Stopped")
+		setCause(CauseScale)
+	case Stopped(prev) && !Stopped(next):
+		parts = append(parts, fmt.Sprintf("Started with %d replicas", next.Replicas))
+		setCause(CauseScale)
+	case prev.Replicas != next.Replicas:
+		parts = append(parts, fmt.Sprintf("Scaled to %d replicas", next.Replicas))
+		setCause(CauseScale)
+	}
+
+	if prev.Port != next.Port {
+		parts = append(parts, fmt.Sprintf("Set port to %d", next.Port))
+		setCause(CauseUpdate)
+	}
+
+	if prev.RoutingEnabled != next.RoutingEnabled {
+		if next.RoutingEnabled {
+			parts = append(parts, "Enabled routing")
+		} else {
+			parts = append(parts, "Disabled routing")
+		}
+
+		setCause(CauseUpdate)
+	}
+
+	if !reflect.DeepEqual(prev.Domains, next.Domains) {
+		parts = append(parts, "Changed domains")
+		setCause(CauseUpdate)
+	}
+
+	if prev.Strategy != next.Strategy {
+		strategy := next.Strategy
+		if strategy == "" {
+			strategy = v1alpha1.RollingStrategy
+		}
+
+		parts = append(parts, fmt.Sprintf("Switched to the %s strategy", strategy))
+		setCause(CauseUpdate)
+	}
+
+	if prev.Maintenance != next.Maintenance {
+		if next.Maintenance {
+			parts = append(parts, "Enabled maintenance")
+		} else {
+			parts = append(parts, "Disabled maintenance")
+		}
+
+		setCause(CauseUpdate)
+	}
+
+	if !reflect.DeepEqual(prev.Sidecars, next.Sidecars) {
+		parts = append(parts, "Changed sidecars")
+		setCause(CauseUpdate)
+	}
+
+	if !reflect.DeepEqual(prev.InitContainers, next.InitContainers) {
+		parts = append(parts, "Changed init containers")
+		setCause(CauseUpdate)
+	}
+
+	if !reflect.DeepEqual(prev.Volumes, next.Volumes) {
+		parts = append(parts, "Changed volumes")
+		setCause(CauseUpdate)
+	}
+
+	if !reflect.DeepEqual(prev.Scheduling, next.Scheduling) {
+		parts = append(parts, "Changed scheduling")
+		setCause(CauseUpdate)
+	}
+
+	// anything else, e.g. the retention policy
+	prev.Image, prev.RestartTime, prev.Replicas, prev.Port = next.Image, next.RestartTime, next.Replicas, next.Port
+	prev.RoutingEnabled, prev.Domains, prev.AppConfigRef = next.RoutingEnabled, next.Domains, next.AppConfigRef
+	prev.Strategy, prev.Maintenance, prev.StoppedFormation = next.Strategy, next.Maintenance, next.StoppedFormation
+	prev.Sidecars, prev.InitContainers, prev.Volumes = next.Sidecars, next.InitContainers, next.Volumes
+	prev.Scheduling = next.Scheduling
+	if !reflect.DeepEqual(prev, next) {
+		parts = append(parts, "Changed settings")
+		setCause(CauseUpdate)
+	}
+
+	return strings.Join(parts, "; "), cause
+}
+
+// DescribeChange works out who changed what and why between the previous
+// revision and the current state of an application. The first revision of
+// an application has no previous revision to compare against.
+func DescribeChange(prev *v1alpha1.ApplicationRevision, app v1alpha1.Application, cfg v1alpha1.ApplicationConfig) v1alpha1.RevisionChange {
+	var prevApp v1alpha1.ApplicationSpec
+	var prevCfg v1alpha1.ApplicationConfigSpec
+	if prev != nil {
+		prevApp = prev.Spec.App
+		prevCfg = prev.Spec.Cfg
+	}
+
+	appSummary, cause := describeAppChange(prevApp, app.Spec)
+	cfgSummary := describeConfigChange(prevCfg.KeyValuePairs, cfg.Spec.KeyValuePairs)
+
+	// the change annotations come from whichever object the user changed,
+	// preferring the app as a rollback changes both
+	annotations := app.Annotations
+	if appSummary == "" && cfgSummary != "" {
+		annotations = cfg.Annotations
+		cause = CauseConfig
+	}
+
+	if explicit := annotations[ChangeCauseAnnotation]; explicit != "" {
+		cause = explicit
+	}
+
+	var parts []string
+	for _, summary := range []string{appSummary, cfgSummary} {
+		if summary != "" {
+			parts = append(parts, summary)
+		}
+	}
+
+	if prev == nil {
+		parts = append([]string{"Created"}, parts...)
+		if cause == "" {
+			cause = CauseDeploy
+		}
+	}
+
+	return v1alpha1.RevisionChange{
+		Author:       annotations[ChangeAuthorAnnotation],
+		Cause:        cause,
+		Summary:      strings.Join(parts, "; "),
+		Message:      annotations[ChangeMessageAnnotation],
+		PromotedFrom: annotations[ChangePromotedFromAnnotation],
+	}
+}
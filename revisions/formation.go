@@ -0,0 +1,46 @@
+package revisions
+
+import (
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+)
+
+// Stopped tells whether the application was stopped with Stop
+func Stopped(spec v1alpha1.ApplicationSpec) bool {
+	return spec.StoppedFormation != nil
+}
+
+// Stop scales the application to zero, remembering the replicas of each
+// process type so Start can bring them back
+func Stop(spec *v1alpha1.ApplicationSpec) {
+	if Stopped(*spec) {
+		return
+	}
+
+	spec.StoppedFormation = map[string]int{
+		constants.DefaultProcessType: spec.Replicas,
+	}
+	spec.Replicas = 0
+}
+
+// Start restores the replicas the application ran before it was stopped
+func Start(spec *v1alpha1.ApplicationSpec) {
+	if !Stopped(*spec) {
+		return
+	}
+
+	spec.Replicas = spec.StoppedFormation[constants.DefaultProcessType]
+	spec.StoppedFormation = nil
+}
+
+// KeepState carries the state that isn't part of a release, maintenance and
+// whether the application is stopped, over to a spec restored from one. A
+// stopped application stays stopped and starts with the restored replicas.
+func KeepState(current v1alpha1.ApplicationSpec, spec *v1alpha1.ApplicationSpec) {
+	spec.Maintenance, spec.MaintenancePage = current.Maintenance, current.MaintenancePage
+
+	Start(spec)
+	if Stopped(current) {
+		Stop(spec)
+	}
+}
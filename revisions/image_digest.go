@@ -0,0 +1,114 @@
+package revisions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PinnedImage returns the revision's image referenced by digest when the
+// digest is known, so deploying it runs exactly what the revision ran
+func PinnedImage(spec v1alpha1.ApplicationRevisionSpec) string {
+	if spec.ImageDigest == "" || strings.Contains(spec.App.Image, "@") {
+		return spec.App.Image
+	}
+
+	repo := spec.App.Image
+	// strip the tag, taking care not to mistake a registry port for one
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+
+	return repo + "@" + spec.ImageDigest
+}
+
+// digestFromImageID pulls the registry digest out of a container status
+// imageID, e.g. docker-pullable://nginx@sha256:abc. Images that were never
+// pulled from a registry only have a local ID and no digest.
+func digestFromImageID(imageID string) string {
+	i := strings.LastIndex(imageID, "@")
+	if i == -1 {
+		return ""
+	}
+
+	return imageID[i+1:]
+}
+
+// RecordImageDigest stores the digest the current revision's image resolved
+// to, taken from the running pods of the application, and returns it. It's a
+// no-op returning an empty digest until a pod running the image is ready.
+func (r *Revision) RecordImageDigest(appName types.NamespacedName, ctx context.Context) (string, error) {
+	log := r.Log.WithValues("source", "revision", "appName", appName.Name, "appNamespace", appName.Namespace)
+
+	var app v1alpha1.Application
+	if err := r.Get(ctx, appName, &app); err != nil {
+		log.Error(err, "Unable to fetch Application")
+		return "", err
+	}
+
+	revNumber, err := CurrentRevisionNumber(app)
+	if err != nil || revNumber == 0 {
+		return "", err
+	}
+
+	var rev v1alpha1.ApplicationRevision
+	revName := types.NamespacedName{Namespace: app.Namespace, Name: RevisionName(app.Name, revNumber)}
+	if err := r.Get(ctx, revName, &rev); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+
+	if rev.Spec.App.Image != app.Spec.Image {
+		return "", nil
+	}
+
+	if rev.Spec.ImageDigest != "" {
+		return rev.Spec.ImageDigest, nil
+	}
+
+	var pods v1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(app.Namespace), client.MatchingLabels{constants.AppLabel: app.Name}); err != nil {
+		log.Error(err, "Unable to list pods")
+		return "", err
+	}
+
+	digest := ""
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			if container.Name != app.Name || container.Image != app.Spec.Image {
+				continue
+			}
+
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name == container.Name && status.Ready {
+					digest = digestFromImageID(status.ImageID)
+				}
+			}
+		}
+
+		if digest != "" {
+			break
+		}
+	}
+
+	if digest == "" {
+		return "", nil
+	}
+
+	log.Info("Recording image digest", "revisionName", rev.Name, "digest", digest)
+	rev.Spec.ImageDigest = digest
+	if err := r.Update(ctx, &rev); err != nil {
+		log.Error(err, "Could not update revision with image digest", "revisionName", rev.Name)
+		return "", err
+	}
+
+	return digest, nil
+}
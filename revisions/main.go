@@ -2,18 +2,21 @@ package revisions
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/json"
-	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/mrferos/feisty/api/v1alpha1"
 	"github.com/mrferos/feisty/constants"
+	"github.com/mrferos/feisty/hashing"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
 )
 
+// RevisionNumberAnnotation held the current revision number before it moved
+// to the application status, it's still read for apps that haven't had a
+// revision recorded since
 var RevisionNumberAnnotation = constants.FeistyAnnotationPrefix + "revision-number"
 
 type Revision struct {
@@ -21,18 +24,82 @@ type Revision struct {
 	Log logr.Logger
 }
 
-func (r *Revision) structMd5(obj interface{}) (string, error) {
-	keyValueJson, err := json.Marshal(obj)
-	if err != nil {
-		return "", err
+// RevisionName returns the name of an application's nth revision
+func RevisionName(appName string, number int) string {
+	return appName + "-v" + strconv.Itoa(number)
+}
+
+// CurrentRevisionNumber returns the number of the application's latest
+// revision, 0 when it doesn't have one yet
+func CurrentRevisionNumber(app v1alpha1.Application) (int, error) {
+	if app.Status.Revision > 0 {
+		return app.Status.Revision, nil
 	}
 
-	md5bytes := md5.Sum([]byte(keyValueJson))
+	if app.Annotations == nil {
+		return 0, nil
+	}
 
-	return fmt.Sprintf("%x", md5bytes), nil
+	val, ok := app.Annotations[RevisionNumberAnnotation]
+	if !ok {
+		return 0, nil
+	}
+
+	return strconv.Atoi(val)
 }
 
-func (r *Revision) CreateIfNeeded(appName types.NamespacedName, ctx context.Context) error {
+// Restore sets the app and config specs back to the ones captured by a
+// revision, with the image pinned by digest. The app keeps its current config
+// reference, the config controller repoints it once the restored config is
+// reconciled. Maintenance and stopping aren't part of a release, so they're
+// left as they are.
+func Restore(rev v1alpha1.ApplicationRevision, app *v1alpha1.Application, cfg *v1alpha1.ApplicationConfig) {
+	current := app.Spec
+	app.Spec = *rev.Spec.App.DeepCopy()
+	app.Spec.Image = PinnedImage(rev.Spec)
+	app.Spec.AppConfigRef = current.AppConfigRef
+	KeepState(current, &app.Spec)
+
+	cfg.Spec = *rev.Spec.Cfg.DeepCopy()
+}
+
+// ContentHash identifies a revision by the specs it captured, so a revision
+// created by an earlier attempt that failed to record it can be recognised.
+// It's worked out from the specs rather than the stored hashes, which older
+// versions computed differently.
+func ContentHash(rev v1alpha1.ApplicationRevision) (string, error) {
+	sum, err := hashing.Sum(struct {
+		App v1alpha1.ApplicationSpec       `json:"app"`
+		Cfg v1alpha1.ApplicationConfigSpec `json:"cfg"`
+	}{rev.Spec.App, rev.Spec.Cfg})
+
+	return hashing.Short(sum), err
+}
+
+// CreateIfNeeded saves the current state of the application and its config as
+// a new revision when it differs from the current revision. It's safe to call
+// from several reconcilers at once: the revision number is claimed by creating
+// the revision, recorded in the application status with optimistic concurrency
+// and the whole thing is retried when another reconciler got there first.
+// It returns the application's current revision.
+func (r *Revision) CreateIfNeeded(appName types.NamespacedName, ctx context.Context) (*NumberedRevision, error) {
+	var current *NumberedRevision
+	err := retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		var err error
+		current, err = r.createIfNeeded(appName, ctx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return current, nil
+}
+
+func (r *Revision) createIfNeeded(appName types.NamespacedName, ctx context.Context) (*NumberedRevision, error) {
 	log := r.Log.WithValues("source", "revision", "appName", appName.Name, "appNamespace", appName.Namespace)
 
 	var app v1alpha1.Application
@@ -40,54 +107,42 @@ func (r *Revision) CreateIfNeeded(appName types.NamespacedName, ctx context.Cont
 
 	if err := r.Get(ctx, appName, &app); err != nil {
 		log.Error(err, "Unable to fetch Application")
-		return err
+		return nil, err
 	}
 
 	// The app may not have any config so we'll ignore a not found error
 	if err := r.Get(ctx, appName, &cfg); err != nil {
 		if client.IgnoreNotFound(err) != nil {
 			log.Error(err, "Unable to fetch ApplicationConfig")
-			return err
+			return nil, err
 		} else {
 			log.Info("Unable to fetch ApplicationConfig", "configName", appName)
 		}
 	}
 
-	currentRevisionNumber := 0
-	var err error
-	if app.Annotations != nil {
-		if val, ok := app.Annotations[RevisionNumberAnnotation]; ok {
-			currentRevisionNumber, err = strconv.Atoi(val)
-			if err != nil {
-				log.Error(err, "There was an error parsing the revision number from the application")
-				return err
-			}
-		}
-	}
-
-	cfgHash := ""
-	appHash := ""
-	revNumber := currentRevisionNumber + 1
-	revName := app.Name + "-v" + strconv.Itoa(revNumber)
-	prevRevName := ""
-
-	if currentRevisionNumber > 0 {
-		prevRevName = app.Name + "-v" + strconv.Itoa(currentRevisionNumber)
+	currentRevisionNumber, err := CurrentRevisionNumber(app)
+	if err != nil {
+		log.Error(err, "There was an error parsing the revision number from the application")
+		return nil, err
 	}
 
-	appHash, err = r.structMd5(app.Spec)
+	appHash, err := hashing.Sum(app.Spec)
 	if err != nil {
 		log.Error(err, "Could not hash the Application")
-		return err
+		return nil, err
 	}
 
+	cfgHash := ""
 	if cfg.Name != "" {
-		cfgHash, err = r.structMd5(cfg.Spec)
+		cfgHash, err = hashing.Sum(cfg.Spec)
+		if err != nil {
+			log.Error(err, "Could not hash the ApplicationConfig")
+			return nil, err
+		}
 	}
 
 	rev := v1alpha1.ApplicationRevision{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      revName,
 			Namespace: app.Namespace,
 		},
 		Spec: v1alpha1.ApplicationRevisionSpec{
@@ -98,41 +153,92 @@ func (r *Revision) CreateIfNeeded(appName types.NamespacedName, ctx context.Cont
 		},
 	}
 
-	prevRev := v1alpha1.ApplicationRevision{}
-	prevNamespacedName := types.NamespacedName{
-		Namespace: app.Namespace,
-		Name:      prevRevName,
+	hash, err := ContentHash(rev)
+	if err != nil {
+		log.Error(err, "Could not hash the revision")
+		return nil, err
+	}
+
+	rev.Labels = map[string]string{
+		constants.AppLabel:          app.Name,
+		constants.RevisionHashLabel: hash,
+	}
+
+	numbered, err := r.ListForApp(app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to list revisions")
+		return nil, err
 	}
 
-	// We may have deleted the prevision religion, so we'll ignore a not found error
-	if prevRevName != "" {
-		if err := r.Get(ctx, prevNamespacedName, &prevRev); err != nil {
-			if client.IgnoreNotFound(err) != nil {
-				log.Error(err, "Unable to fetch previous ApplicationRevision")
-				return err
-			} else {
-				log.Info("Unable to fetch previous ApplicationRevision", "prevName", prevRevName)
-			}
+	var prevRev *v1alpha1.ApplicationRevision
+	var latestRev NumberedRevision
+	prevHash, latestHash := "", ""
+	latest := currentRevisionNumber
+	for i, n := range numbered {
+		if n.Number != currentRevisionNumber && n.Number <= latest {
+			continue
+		}
+
+		revHash, err := ContentHash(n.Revision)
+		if err != nil {
+			log.Error(err, "Could not hash the revision", "revisionName", n.Revision.Name)
+			return nil, err
+		}
+
+		if n.Number == currentRevisionNumber {
+			prevRev = &numbered[i].Revision
+			prevHash = revHash
+		} else {
+			latest = n.Number
+			latestRev = n
+			latestHash = revHash
 		}
 	}
 
-	// If the hashes don't match then we'll save the new revision
-	if rev.Spec.AppHash != prevRev.Spec.AppHash || rev.Spec.CfgHash != prevRev.Spec.CfgHash {
-		log.Info("Saving new revision ", "revisionName", revName)
-		if err := r.Create(ctx, &rev); err != nil {
-			log.Error(err, "Could not create revision", "revisionName", revName)
-			return err
+	// We may have deleted the previous revision, in which case there's nothing to compare against
+	if prevRev != nil && prevHash == hash {
+		// apps from before the revision number moved to the status only have the annotation
+		if app.Status.Revision != currentRevisionNumber {
+			return &NumberedRevision{currentRevisionNumber, *prevRev}, r.recordRevision(app, currentRevisionNumber, ctx)
 		}
 
-		if app.Annotations == nil {
-			app.Annotations = map[string]string{}
+		return &NumberedRevision{currentRevisionNumber, *prevRev}, nil
+	}
+
+	// A revision newer than the current one was created but never recorded,
+	// either by a reconcile that failed half way or one that is still going.
+	// The same content is adopted, anything else is skipped over.
+	if latest > currentRevisionNumber && latestHash == hash {
+		log.Info("Recording existing revision", "revisionName", latestRev.Revision.Name)
+		return &latestRev, r.recordRevision(app, latest, ctx)
+	}
+
+	revNumber := latest + 1
+	rev.Name = RevisionName(app.Name, revNumber)
+	rev.Spec.Change = DescribeChange(prevRev, app, cfg)
+
+	log.Info("Saving new revision ", "revisionName", rev.Name)
+	if err := r.Create(ctx, &rev); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			log.Error(err, "Could not create revision", "revisionName", rev.Name)
 		}
 
-		app.Annotations[RevisionNumberAnnotation] = strconv.Itoa(revNumber)
-		if err := r.Update(ctx, &app); err != nil {
-			log.Error(err, "Could not update application with current revision number")
-			return err
+		return nil, err
+	}
+
+	return &NumberedRevision{revNumber, rev}, r.recordRevision(app, revNumber, ctx)
+}
+
+// recordRevision makes the given revision the application's current one. The
+// update fails with a conflict when the application changed since it was read.
+func (r *Revision) recordRevision(app v1alpha1.Application, revNumber int, ctx context.Context) error {
+	app.Status.Revision = revNumber
+	if err := r.Status().Update(ctx, &app); err != nil {
+		if !apierrors.IsConflict(err) {
+			r.Log.Error(err, "Could not update application with current revision number", "appName", app.Name)
 		}
+
+		return err
 	}
 
 	return nil
@@ -0,0 +1,145 @@
+package revisions
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrferos/feisty/api/v1alpha1"
+	"github.com/mrferos/feisty/constants"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PinnedAnnotation marks a revision that must never be pruned
+var PinnedAnnotation = constants.FeistyAnnotationPrefix + "pinned"
+
+// RetentionPolicy is the operator wide revision retention, applications can
+// override it with spec.revisionRetention
+type RetentionPolicy struct {
+	Count  int
+	MaxAge time.Duration
+}
+
+func (p RetentionPolicy) forApp(app v1alpha1.Application) RetentionPolicy {
+	if app.Spec.RevisionRetention == nil {
+		return p
+	}
+
+	policy := RetentionPolicy{Count: app.Spec.RevisionRetention.Count}
+	if app.Spec.RevisionRetention.MaxAge != nil {
+		policy.MaxAge = app.Spec.RevisionRetention.MaxAge.Duration
+	}
+
+	return policy
+}
+
+// NumberedRevision is a revision along with its number
+type NumberedRevision struct {
+	Number   int
+	Revision v1alpha1.ApplicationRevision
+}
+
+// ListForApp returns the revisions of an application, newest first
+func (r *Revision) ListForApp(app v1alpha1.Application, ctx context.Context) ([]NumberedRevision, error) {
+	var revisionList v1alpha1.ApplicationRevisionList
+	if err := r.List(ctx, &revisionList, client.InNamespace(app.Namespace)); err != nil {
+		return nil, err
+	}
+
+	prefix := app.Name + "-v"
+	var numbered []NumberedRevision
+	for _, rev := range revisionList.Items {
+		if !strings.HasPrefix(rev.Name, prefix) {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.TrimPrefix(rev.Name, prefix))
+		if err != nil {
+			continue
+		}
+
+		numbered = append(numbered, NumberedRevision{number, rev})
+	}
+
+	sort.Slice(numbered, func(i, j int) bool {
+		return numbered[i].Number > numbered[j].Number
+	})
+
+	return numbered, nil
+}
+
+// IsPinned returns whether a revision is protected from pruning
+func IsPinned(rev v1alpha1.ApplicationRevision) bool {
+	return rev.Annotations[PinnedAnnotation] == "true"
+}
+
+// Prune deletes the revisions of an application that fall outside its
+// retention policy and returns their numbers. The current revision, the one
+// before it and pinned revisions are always kept.
+func (r *Revision) Prune(appName types.NamespacedName, defaults RetentionPolicy, ctx context.Context) ([]int, error) {
+	log := r.Log.WithValues("source", "revision", "appName", appName.Name, "appNamespace", appName.Namespace)
+
+	var app v1alpha1.Application
+	if err := r.Get(ctx, appName, &app); err != nil {
+		log.Error(err, "Unable to fetch Application")
+		return nil, err
+	}
+
+	policy := defaults.forApp(app)
+	if policy.Count <= 0 && policy.MaxAge <= 0 {
+		return nil, nil
+	}
+
+	current, err := CurrentRevisionNumber(app)
+	if err != nil || current == 0 {
+		return nil, err
+	}
+
+	numbered, err := r.ListForApp(app, ctx)
+	if err != nil {
+		log.Error(err, "Unable to list revisions")
+		return nil, err
+	}
+
+	var pruned []int
+	kept := 0
+	previousKept := false
+	for _, n := range numbered {
+		keep := false
+		switch {
+		case n.Number > current:
+			// created after the app was read, leave it for the next prune
+			keep = true
+		case n.Number == current:
+			keep = true
+		case !previousKept:
+			keep = true
+			previousKept = true
+		case IsPinned(n.Revision):
+			keep = true
+		default:
+			tooMany := policy.Count > 0 && kept >= policy.Count
+			tooOld := policy.MaxAge > 0 && time.Since(n.Revision.CreationTimestamp.Time) > policy.MaxAge
+			keep = !tooMany && !tooOld
+		}
+
+		if keep {
+			kept++
+			continue
+		}
+
+		rev := n.Revision
+		if err := r.Delete(ctx, &rev); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Could not prune revision", "revisionName", rev.Name)
+			return pruned, err
+		}
+
+		log.Info("Pruned revision", "revisionName", rev.Name)
+		pruned = append(pruned, n.Number)
+	}
+
+	return pruned, nil
+}
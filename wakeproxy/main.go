@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/mrferos/feisty/wakeproxy/proxy"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = feistyv1alpha1.AddToScheme(scheme)
+}
+
+// The wake-up proxy gets the ingress traffic of the applications that sleep
+// when idle, see the proxy package. The operator's --wake-proxy-service flag
+// points at the Service in front of it.
+func main() {
+	var addr string
+	var metricsAddr string
+	p := &proxy.Proxy{Log: ctrl.Log.WithName("wakeproxy")}
+	flag.StringVar(&addr, "addr", ":8080", "The address the proxy binds to.")
+	flag.StringVar(&metricsAddr, "metrics-addr", "0", "The address the metric endpoint binds to, 0 disables it.")
+	flag.DurationVar(&p.WakeTimeout, "wake-timeout", proxy.DefaultWakeTimeout,
+		"How long a request is held while the application wakes up.")
+	flag.DurationVar(&p.ActivityInterval, "activity-interval", proxy.DefaultActivityInterval,
+		"How often the requests of an application are reported to the operator.")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: metricsAddr,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	p.Client = mgr.GetClient()
+	server := &http.Server{Addr: addr, Handler: p}
+	if err := mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		go func() {
+			<-stop
+			_ = server.Close()
+		}()
+
+		setupLog.Info("serving", "addr", addr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+
+		return nil
+	})); err != nil {
+		setupLog.Error(err, "unable to add proxy")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	feistyv1alpha1 "github.com/mrferos/feisty/api/v1alpha1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	DefaultWakeTimeout      = 2 * time.Minute
+	DefaultActivityInterval = time.Minute
+	readyCheckInterval      = 500 * time.Millisecond
+	// wakeRetryInterval keeps the requests held for a sleeping application
+	// from all waking it up again while the cache catches up
+	wakeRetryInterval = 5 * time.Second
+	servicePort       = 80
+)
+
+// Proxy sits between the ingress and the applications that sleep when idle.
+// It reports the requests it gets to the operator, at most once per
+// ActivityInterval, and wakes a sleeping application up holding the request
+// until one of its pods is ready.
+type Proxy struct {
+	// Client reads applications and endpoints, it's expected to be cached
+	Client client.Client
+	Log    logr.Logger
+	// WakeTimeout is how long a request is held while the application wakes up
+	WakeTimeout time.Duration
+	// ActivityInterval is how often the requests of an application are reported
+	ActivityInterval time.Duration
+
+	mu       sync.Mutex
+	reported map[types.NamespacedName]time.Time
+	proxies  map[types.NamespacedName]*httputil.ReverseProxy
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	app, err := p.findApplication(host, ctx)
+	if err != nil {
+		p.Log.Error(err, "Unable to find application", "host", host)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	if app == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	log := p.Log.WithValues("application", types.NamespacedName{Namespace: app.Namespace, Name: app.Name})
+	if err := p.reportActivity(*app, ctx); err != nil {
+		log.Error(err, "Could not report activity")
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.wakeTimeout())
+	defer cancel()
+
+	if err := p.waitUntilReady(*app, waitCtx); err != nil {
+		log.Error(err, "Application didn't wake up in time")
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	p.reverseProxy(*app).ServeHTTP(w, req)
+}
+
+// findApplication returns the application serving a host, if it sleeps when
+// idle
+func (p *Proxy) findApplication(host string, ctx context.Context) (*feistyv1alpha1.Application, error) {
+	var apps feistyv1alpha1.ApplicationList
+	if err := p.Client.List(ctx, &apps); err != nil {
+		return nil, err
+	}
+
+	for i, app := range apps.Items {
+		if app.Spec.SleepAfter == nil {
+			continue
+		}
+
+		for _, domain := range app.Spec.Domains {
+			if domain.Host == host {
+				return &apps.Items[i], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// reportActivity records the request in the application status, a sleeping
+// application is woken up right away
+func (p *Proxy) reportActivity(app feistyv1alpha1.Application, ctx context.Context) error {
+	name := types.NamespacedName{Namespace: app.Namespace, Name: app.Name}
+	sleeping := app.Status.Sleep != nil && app.Status.Sleep.Asleep
+
+	p.mu.Lock()
+	if p.reported == nil {
+		p.reported = map[types.NamespacedName]time.Time{}
+	}
+
+	interval := p.activityInterval()
+	if sleeping {
+		interval = wakeRetryInterval
+	}
+
+	if time.Since(p.reported[name]) < interval {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.reported[name] = time.Now()
+	p.mu.Unlock()
+
+	patch := client.MergeFrom(app.DeepCopy())
+	now := metav1.Now()
+	if app.Status.Sleep == nil {
+		app.Status.Sleep = &feistyv1alpha1.SleepStatus{}
+	}
+
+	app.Status.Sleep.LastActivityTime = &now
+	if sleeping {
+		p.Log.Info("Waking application up", "application", name)
+		app.Status.Sleep.Asleep = false
+		app.Status.Sleep.WakeTime = &now
+	}
+
+	return p.Client.Status().Patch(ctx, &app, patch)
+}
+
+// waitUntilReady holds on until the application's Service has a ready pod,
+// waking the application up again if the operator put it to sleep meanwhile
+func (p *Proxy) waitUntilReady(app feistyv1alpha1.Application, ctx context.Context) error {
+	name := types.NamespacedName{Namespace: app.Namespace, Name: app.Name}
+	for {
+		var endpoints v12.Endpoints
+		if err := p.Client.Get(ctx, name, &endpoints); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		for _, subset := range endpoints.Subsets {
+			if len(subset.Addresses) > 0 {
+				return nil
+			}
+		}
+
+		var current feistyv1alpha1.Application
+		if err := p.Client.Get(ctx, name, &current); err != nil {
+			return err
+		}
+
+		if current.Status.Sleep != nil && current.Status.Sleep.Asleep {
+			if err := p.reportActivity(current, ctx); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no pod of %s became ready: %v", name, ctx.Err())
+		case <-time.After(readyCheckInterval):
+		}
+	}
+}
+
+func (p *Proxy) reverseProxy(app feistyv1alpha1.Application) *httputil.ReverseProxy {
+	name := types.NamespacedName{Namespace: app.Namespace, Name: app.Name}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proxies == nil {
+		p.proxies = map[types.NamespacedName]*httputil.ReverseProxy{}
+	}
+
+	if proxy, ok := p.proxies[name]; ok {
+		return proxy
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s.%s.svc:%d", app.Name, app.Namespace, servicePort),
+	})
+	p.proxies[name] = proxy
+
+	return proxy
+}
+
+func (p *Proxy) wakeTimeout() time.Duration {
+	if p.WakeTimeout > 0 {
+		return p.WakeTimeout
+	}
+
+	return DefaultWakeTimeout
+}
+
+func (p *Proxy) activityInterval() time.Duration {
+	if p.ActivityInterval > 0 {
+		return p.ActivityInterval
+	}
+
+	return DefaultActivityInterval
+}
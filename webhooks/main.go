@@ -0,0 +1,135 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"github.com/mrferos/feisty/revisions"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var ChangeAuthorPath = "/mutate-change-author"
+
+// +kubebuilder:webhook:path=/mutate-change-author,mutating=true,failurePolicy=ignore,groups=feisty.paas.feisty.dev,resources=applications;applicationconfigs,verbs=create;update,versions=v1alpha1,name=changeauthor.paas.feisty.dev
+
+// ChangeAuthor records the requesting user as the change author on
+// Applications and ApplicationConfigs so revisions can't be attributed to
+// whoever the CLI claims to be. Updates that leave the spec alone, like the
+// controllers' own bookkeeping, keep the previous change annotations.
+type ChangeAuthor struct {
+	Log logr.Logger
+}
+
+// specOf returns the part of the object a user changes, leaving out the
+// config reference the controller maintains
+func specOf(obj *unstructured.Unstructured) interface{} {
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	copied := map[string]interface{}{}
+	for k, v := range spec {
+		if k != "appConfigRef" {
+			copied[k] = v
+		}
+	}
+
+	return copied
+}
+
+// changeAnnotations works out the annotations of an object being created, or
+// updated from old, by the given user
+func changeAnnotations(obj *unstructured.Unstructured, old *unstructured.Unstructured, username string) map[string]string {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if old == nil {
+		annotations[revisions.ChangeAuthorAnnotation] = username
+		return annotations
+	}
+
+	oldAnnotations := old.GetAnnotations()
+	if apiequality.Semantic.DeepEqual(specOf(obj), specOf(old)) {
+		for _, key := range revisions.ChangeAnnotations {
+			if val, ok := oldAnnotations[key]; ok {
+				annotations[key] = val
+			} else {
+				delete(annotations, key)
+			}
+		}
+
+		return annotations
+	}
+
+	// a cause or message left over from an earlier change doesn't describe
+	// this one, a request that set them itself changed the change ID
+	if annotations[revisions.ChangeIDAnnotation] == oldAnnotations[revisions.ChangeIDAnnotation] {
+		for _, key := range []string{revisions.ChangeCauseAnnotation, revisions.ChangeMessageAnnotation, revisions.ChangePromotedFromAnnotation} {
+			if val, ok := annotations[key]; ok && val == oldAnnotations[key] {
+				delete(annotations, key)
+			}
+		}
+	}
+
+	annotations[revisions.ChangeAuthorAnnotation] = username
+
+	return annotations
+}
+
+func (h *ChangeAuthor) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var old *unstructured.Unstructured
+	if req.Operation == admissionv1beta1.Update {
+		old = &unstructured.Unstructured{}
+		if err := json.Unmarshal(req.OldObject.Raw, &old.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	annotations := changeAnnotations(obj, old, req.UserInfo.Username)
+
+	h.Log.V(1).Info("Recording change author", "kind", req.Kind.Kind, "name", req.Name, "namespace", req.Namespace,
+		"author", annotations[revisions.ChangeAuthorAnnotation])
+
+	obj.SetAnnotations(annotations)
+	patched, err := json.Marshal(obj.Object)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, patched)
+}
+
+// Register adds the webhooks to the manager's webhook server
+func Register(server *webhook.Server, log logr.Logger) {
+	server.Register(ChangeAuthorPath, &webhook.Admission{Handler: &ChangeAuthor{Log: log}})
+}
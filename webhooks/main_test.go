@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/mrferos/feisty/revisions"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func object(image string, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"image": image},
+	}}
+	obj.SetAnnotations(annotations)
+
+	return obj
+}
+
+func TestChangeAnnotations(t *testing.T) {
+	rollback := map[string]string{
+		revisions.ChangeCauseAnnotation: revisions.CauseRollback,
+		revisions.ChangeIDAnnotation:    "a",
+	}
+
+	tests := []struct {
+		name string
+		old  *unstructured.Unstructured
+		obj  *unstructured.Unstructured
+		want map[string]string
+	}{
+		{
+			name: "create records the author",
+			obj:  object("app:v1", nil),
+			want: map[string]string{revisions.ChangeAuthorAnnotation: "alice"},
+		},
+		{
+			name: "a second rollback keeps its cause",
+			old:  object("app:v1", rollback),
+			obj: object("app:v2", map[string]string{
+				revisions.ChangeCauseAnnotation: revisions.CauseRollback,
+				revisions.ChangeIDAnnotation:    "b",
+			}),
+			want: map[string]string{
+				revisions.ChangeAuthorAnnotation: "alice",
+				revisions.ChangeCauseAnnotation:  revisions.CauseRollback,
+				revisions.ChangeIDAnnotation:     "b",
+			},
+		},
+		{
+			name: "a repeated message is kept",
+			old: object("app:v1", map[string]string{
+				revisions.ChangeMessageAnnotation: "hotfix",
+				revisions.ChangeIDAnnotation:      "a",
+			}),
+			obj: object("app:v2", map[string]string{
+				revisions.ChangeMessageAnnotation: "hotfix",
+				revisions.ChangeIDAnnotation:      "b",
+			}),
+			want: map[string]string{
+				revisions.ChangeAuthorAnnotation:  "alice",
+				revisions.ChangeMessageAnnotation: "hotfix",
+				revisions.ChangeIDAnnotation:      "b",
+			},
+		},
+		{
+			name: "promoting the same release again keeps the promotion",
+			old: object("app:v1", map[string]string{
+				revisions.ChangeCauseAnnotation:        revisions.CausePromote,
+				revisions.ChangePromotedFromAnnotation: "staging/app-v3",
+				revisions.ChangeIDAnnotation:           "a",
+			}),
+			obj: object("app:v2", map[string]string{
+				revisions.ChangeCauseAnnotation:        revisions.CausePromote,
+				revisions.ChangePromotedFromAnnotation: "staging/app-v3",
+				revisions.ChangeIDAnnotation:           "b",
+			}),
+			want: map[string]string{
+				revisions.ChangeAuthorAnnotation:       "alice",
+				revisions.ChangeCauseAnnotation:        revisions.CausePromote,
+				revisions.ChangePromotedFromAnnotation: "staging/app-v3",
+				revisions.ChangeIDAnnotation:           "b",
+			},
+		},
+		{
+			name: "a change that kept the change ID drops the leftovers",
+			old:  object("app:v1", rollback),
+			obj:  object("app:v2", rollback),
+			want: map[string]string{
+				revisions.ChangeAuthorAnnotation: "alice",
+				revisions.ChangeIDAnnotation:     "a",
+			},
+		},
+		{
+			name: "a change that kept the change ID keeps a cause it set",
+			old:  object("app:v1", rollback),
+			obj: object("app:v2", map[string]string{
+				revisions.ChangeCauseAnnotation: revisions.CauseConfig,
+				revisions.ChangeIDAnnotation:    "a",
+			}),
+			want: map[string]string{
+				revisions.ChangeAuthorAnnotation: "alice",
+				revisions.ChangeCauseAnnotation:  revisions.CauseConfig,
+				revisions.ChangeIDAnnotation:     "a",
+			},
+		},
+		{
+			name: "bookkeeping keeps the previous annotations",
+			old: object("app:v1", map[string]string{
+				revisions.ChangeAuthorAnnotation: "bob",
+				revisions.ChangeCauseAnnotation:  revisions.CauseRollback,
+				revisions.ChangeIDAnnotation:     "a",
+			}),
+			obj: object("app:v1", map[string]string{"other": "value"}),
+			want: map[string]string{
+				"other":                          "value",
+				revisions.ChangeAuthorAnnotation: "bob",
+				revisions.ChangeCauseAnnotation:  revisions.CauseRollback,
+				revisions.ChangeIDAnnotation:     "a",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeAnnotations(tt.obj, tt.old, "alice")
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s: got %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}